@@ -0,0 +1,147 @@
+package authoring
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bgraf/rueckblick/data"
+)
+
+func TestNormalizeTitle(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"Walk in the park", "walk-in-the-park"},
+		{"  Über   den Berg! ", "über-den-berg-"},
+		{"2023: Tag 1", "2023-tag-1"},
+		{"?!", ""},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeTitle(tt.title); got != tt.want {
+			t.Errorf("NormalizeTitle(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestReadEntrySpec(t *testing.T) {
+	spec, err := ReadEntrySpec(strings.NewReader(`{"title": "Walk", "append_tracks": false}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.Title != "Walk" || spec.AppendTracks || !spec.AppendGallery {
+		t.Errorf("unexpected spec %+v", spec)
+	}
+}
+
+func TestCreateEntry(t *testing.T) {
+	journal := t.TempDir()
+	photos := t.TempDir()
+
+	writeTestJPEG(t, filepath.Join(photos, "a.jpg"))
+
+	err := os.WriteFile(filepath.Join(photos, "track.gpx"), []byte("<gpx></gpx>"), 0o666)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spec := DefaultEntrySpec()
+	spec.Date = "2023-04-02"
+	spec.Title = "Walk in the park"
+	spec.Tags = map[string][]string{"location": {"Osnabrück"}}
+	spec.Photos = photos
+	spec.Preview = "a.jpg"
+
+	entry, err := CreateEntry(journal, spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantFile := filepath.Join(journal, "2023", "2023-04-02-walk-in-the-park", "doc_2023-04-02.md")
+	if entry.File != wantFile {
+		t.Fatalf("entry file '%s', want '%s'", entry.File, wantFile)
+	}
+
+	doc, err := OpenDocument(entry.File)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fm data.FrontMatter
+	if err := doc.Decode(&fm); err != nil {
+		t.Fatal(err)
+	}
+
+	if fm.Title != spec.Title || fm.Preview == "" || len(fm.Tags["location"]) != 1 {
+		t.Errorf("unexpected front matter %+v", fm)
+	}
+
+	for _, element := range []string{`track="track.gpx"`, "<rb-gallery"} {
+		if !strings.Contains(string(doc.Bytes()), element) {
+			t.Errorf("document lacks %s:\n%s", element, doc.Bytes())
+		}
+	}
+
+	for _, name := range []string{"track.gpx", fm.Preview, filepath.Join(DefaultGalleryOptions().Directory, "a.jpg")} {
+		if _, err := os.Stat(filepath.Join(entry.Directory, name)); err != nil {
+			t.Error(err)
+		}
+	}
+
+	if _, err := CreateEntry(journal, spec); !errors.Is(err, ErrEntryExists) {
+		t.Errorf("got error %v, want ErrEntryExists", err)
+	}
+}
+
+func TestCreateEntryErrors(t *testing.T) {
+	tests := []struct {
+		spec EntrySpec
+		want error
+	}{
+		{EntrySpec{Title: " "}, ErrNoTitle},
+		{EntrySpec{Title: "!"}, ErrEmptyNormalizedTitle},
+	}
+
+	for _, tt := range tests {
+		if _, err := CreateEntry(t.TempDir(), tt.spec); !errors.Is(err, tt.want) {
+			t.Errorf("got error %v, want %v", err, tt.want)
+		}
+	}
+
+	spec := EntrySpec{Title: "Walk", Photos: t.TempDir(), AppendGallery: true}
+
+	var stepErr *StepError
+	if _, err := CreateEntry(t.TempDir(), spec); !errors.As(err, &stepErr) || stepErr.Step != "gallery" {
+		t.Errorf("got error %v, want gallery step error", err)
+	}
+}
+
+func writeTestJPEG(t *testing.T, path string) {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	for x := range 64 {
+		for y := range 48 {
+			img.Set(x, y, color.RGBA{uint8(x * 4), uint8(y * 4), 128, 255})
+		}
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() { _ = f.Close() }()
+
+	if err := jpeg.Encode(f, img, nil); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,3 @@
+// Package authoring creates journal entries and adds tracks, files, galleries and preview
+// images to them.
+package authoring
@@ -0,0 +1,271 @@
+package authoring
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/bgraf/rueckblick/data"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrUnsupportedFrontMatter reports front matter which is not a block mapping with keys in
+// the first column.
+var ErrUnsupportedFrontMatter = errors.New("front matter is not a block mapping")
+
+// Document is a markdown document whose front matter fields are edited in place. Apart
+// from the edited fields, the front matter including comments and key order and the
+// body stay byte-identical.
+type Document struct {
+	Path   string
+	source []byte
+}
+
+// OpenDocument reads the markdown document.
+func OpenDocument(path string) (*Document, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Document{Path: path, source: source}, nil
+}
+
+// ParseDocument returns the document of the source without a path.
+func ParseDocument(source []byte) *Document {
+	return &Document{source: bytes.Clone(source)}
+}
+
+// EditDocument opens the single markdown document in the directory, applies the edit and
+// saves it.
+func EditDocument(directory string, edit func(doc *Document) error) error {
+	file, err := FindDocument(directory)
+	if err != nil {
+		return err
+	}
+
+	doc, err := OpenDocument(file)
+	if err != nil {
+		return err
+	}
+
+	if err := edit(doc); err != nil {
+		return err
+	}
+
+	return doc.Save()
+}
+
+// Bytes returns the source of the document.
+func (d *Document) Bytes() []byte {
+	return d.source
+}
+
+// Decode decodes the front matter into v, e.g., a data.FrontMatter.
+func (d *Document) Decode(v any) error {
+	fm, _, _, err := d.split()
+	if err != nil {
+		return err
+	}
+
+	return yaml.Unmarshal(fm, v)
+}
+
+// Set sets the front matter field to the value. Fields not yet present are added after
+// the last field.
+func (d *Document) Set(key string, value any) error {
+	var valueNode yaml.Node
+	if err := valueNode.Encode(value); err != nil {
+		return fmt.Errorf("encode '%s': %w", key, err)
+	}
+
+	return d.edit(key, func(old *field) ([]byte, error) {
+		keyNode := &yaml.Node{Kind: yaml.ScalarNode, Value: key}
+
+		// Keep the comment after the field.
+		if old != nil {
+			keyNode.LineComment = old.key.LineComment
+			if old.value.Kind == yaml.ScalarNode && valueNode.Kind == yaml.ScalarNode {
+				valueNode.LineComment = old.value.LineComment
+			}
+		}
+
+		return encodeField(keyNode, &valueNode)
+	})
+}
+
+// Delete removes the front matter field if present.
+func (d *Document) Delete(key string) error {
+	return d.edit(key, func(old *field) ([]byte, error) {
+		return nil, nil
+	})
+}
+
+// Append appends text to the body of the document.
+func (d *Document) Append(text string) {
+	d.source = append(d.source, text...)
+}
+
+// Save replaces the file of the document. The permissions of the file are kept.
+func (d *Document) Save() error {
+	if d.Path == "" {
+		return errors.New("document without path")
+	}
+
+	fi, err := os.Stat(d.Path)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first, such that the document is not lost on failure.
+	f, err := os.CreateTemp(filepath.Dir(d.Path), "tmp-rb.*.md")
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(d.source)
+	err = errors.Join(err, f.Close())
+	if err == nil {
+		err = os.Chmod(f.Name(), fi.Mode())
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), d.Path)
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+
+	return nil
+}
+
+// split returns the front matter source, the source before and after it. For a document
+// without front matter, an empty one is put before the body.
+func (d *Document) split() (fm, before, after []byte, err error) {
+	fm, rest, err := data.SplitFrontMatterSource(d.source)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if fm == nil {
+		return []byte("\n"), []byte("---"), append([]byte("---\n\n"), d.source...), nil
+	}
+
+	start := len(d.source) - len(rest) - len("---") - len(fm)
+
+	return fm, d.source[:start], d.source[start+len(fm):], nil
+}
+
+// field is a top-level field of the front matter.
+type field struct {
+	key, value *yaml.Node
+	next       int // Line of the next key, zero for the last field
+}
+
+// edit replaces the lines of the field by the source returned by replace, which is passed
+// nil if the field is not present.
+func (d *Document) edit(key string, replace func(old *field) ([]byte, error)) error {
+	fm, before, after, err := d.split()
+	if err != nil {
+		return err
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(fm, &root); err != nil {
+		return fmt.Errorf("parse front matter: %w", err)
+	}
+
+	var mapping *yaml.Node
+	if len(root.Content) > 0 {
+		mapping = root.Content[0]
+		if mapping.Kind != yaml.MappingNode || mapping.Style&yaml.FlowStyle != 0 {
+			return ErrUnsupportedFrontMatter
+		}
+	}
+
+	var old *field
+	if mapping != nil {
+		for i := 0; i < len(mapping.Content); i += 2 {
+			k := mapping.Content[i]
+			if k.Column != 1 {
+				return ErrUnsupportedFrontMatter
+			}
+
+			if k.Value == key {
+				old = &field{key: k, value: mapping.Content[i+1]}
+				if i+2 < len(mapping.Content) {
+					old.next = mapping.Content[i+2].Line
+				}
+			}
+		}
+	}
+
+	replacement, err := replace(old)
+	if err != nil {
+		return err
+	}
+
+	lines := bytes.SplitAfter(fm, []byte("\n"))
+
+	// Lines [first, last) hold the field, which is appended after the last line
+	// otherwise.
+	first, last := len(lines), len(lines)
+	if len(lines[len(lines)-1]) == 0 {
+		first, last = len(lines)-1, len(lines)-1
+	}
+
+	if old != nil {
+		first = old.key.Line - 1
+		if old.next > 0 {
+			last = old.next - 1
+		}
+
+		// Blank lines and comments before the next key belong to the next key.
+		for last > first+1 && isSeparatorLine(lines[last-1]) {
+			last--
+		}
+	} else if replacement == nil {
+		return nil
+	}
+
+	if first > 0 && !bytes.HasSuffix(lines[first-1], []byte("\n")) {
+		replacement = append([]byte("\n"), replacement...)
+	}
+
+	var b bytes.Buffer
+	b.Write(before)
+	b.Write(bytes.Join(lines[:first], nil))
+	b.Write(replacement)
+	b.Write(bytes.Join(lines[last:], nil))
+	b.Write(after)
+
+	d.source = b.Bytes()
+
+	return nil
+}
+
+// isSeparatorLine reports whether the line is blank or a comment in the first column.
+func isSeparatorLine(line []byte) bool {
+	return len(bytes.TrimSpace(line)) == 0 || line[0] == '#'
+}
+
+// encodeField returns the YAML source of a mapping of the key to the value.
+func encodeField(key, value *yaml.Node) ([]byte, error) {
+	var b bytes.Buffer
+
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+
+	err := enc.Encode(&yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{key, value}})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
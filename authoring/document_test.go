@@ -0,0 +1,160 @@
+package authoring
+
+import (
+	"errors"
+	"testing"
+)
+
+const testDocument = `---
+# Written by hand
+title: Walk   # keep me
+date: 2023-04-02
+tags:
+  location: [Osnabrück]
+  people:
+  - Anna
+
+# Unknown to rueckblick
+weather: sunny
+---
+Body
+
+  with trailing space
+`
+
+func TestDocumentEdit(t *testing.T) {
+	tests := []struct {
+		name string
+		edit func(doc *Document) error
+		want string
+	}{
+		{
+			name: "replace scalar",
+			edit: func(doc *Document) error { return doc.Set("title", "Run") },
+			want: `---
+# Written by hand
+title: Run # keep me
+date: 2023-04-02
+tags:
+  location: [Osnabrück]
+  people:
+  - Anna
+
+# Unknown to rueckblick
+weather: sunny
+---
+Body
+
+  with trailing space
+`,
+		},
+		{
+			name: "replace mapping",
+			edit: func(doc *Document) error {
+				return doc.Set("tags", map[string][]string{"location": {"Belm"}})
+			},
+			want: `---
+# Written by hand
+title: Walk   # keep me
+date: 2023-04-02
+tags:
+  location:
+    - Belm
+
+# Unknown to rueckblick
+weather: sunny
+---
+Body
+
+  with trailing space
+`,
+		},
+		{
+			name: "add field",
+			edit: func(doc *Document) error { return doc.Set("preview", "preview.jpg") },
+			want: `---
+# Written by hand
+title: Walk   # keep me
+date: 2023-04-02
+tags:
+  location: [Osnabrück]
+  people:
+  - Anna
+
+# Unknown to rueckblick
+weather: sunny
+preview: preview.jpg
+---
+Body
+
+  with trailing space
+`,
+		},
+		{
+			name: "delete field",
+			edit: func(doc *Document) error { return doc.Delete("date") },
+			want: `---
+# Written by hand
+title: Walk   # keep me
+tags:
+  location: [Osnabrück]
+  people:
+  - Anna
+
+# Unknown to rueckblick
+weather: sunny
+---
+Body
+
+  with trailing space
+`,
+		},
+		{
+			name: "append",
+			edit: func(doc *Document) error {
+				doc.Append("<rb-gallery></rb-gallery>\n")
+				return doc.Delete("missing")
+			},
+			want: testDocument + "<rb-gallery></rb-gallery>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := ParseDocument([]byte(testDocument))
+			if err := tt.edit(doc); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := string(doc.Bytes()); got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDocumentWithoutFrontMatter(t *testing.T) {
+	doc := ParseDocument([]byte("Body\n"))
+
+	var fm map[string]any
+	if err := doc.Decode(&fm); err != nil || len(fm) != 0 {
+		t.Fatalf("got %v, %v", fm, err)
+	}
+
+	if err := doc.Set("abstract", "Short"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "---\nabstract: Short\n---\n\nBody\n"
+	if got := string(doc.Bytes()); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestDocumentFlowFrontMatter(t *testing.T) {
+	doc := ParseDocument([]byte("---\n{title: Walk}\n---\n"))
+
+	if err := doc.Set("title", "Run"); !errors.Is(err, ErrUnsupportedFrontMatter) {
+		t.Errorf("got error %v, want ErrUnsupportedFrontMatter", err)
+	}
+}
@@ -0,0 +1,250 @@
+package authoring
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/bgraf/rueckblick/config"
+	"github.com/bgraf/rueckblick/data"
+	"github.com/bgraf/rueckblick/filesystem"
+	"github.com/bgraf/rueckblick/util/dates"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	ErrNoTitle              = errors.New("no title given")
+	ErrEmptyNormalizedTitle = errors.New("empty normalized title, try letters and digits")
+	ErrEntryExists          = errors.New("entry already exists")
+)
+
+// EntrySpec describes an entry to create.
+type EntrySpec struct {
+	Date     string              `yaml:"date"` // YYYY-MM-DD or today, today if empty
+	Title    string              `yaml:"title"`
+	Tags     map[string][]string `yaml:"tags"` // Tag names by category
+	Abstract string              `yaml:"abstract"`
+	Author   string              `yaml:"author"`
+
+	// Directory of photos, tracks and extra files to add to the entry, optional.
+	Photos        string `yaml:"photos"`
+	AppendTracks  bool   `yaml:"append_tracks"`
+	AppendGallery bool   `yaml:"append_gallery"`
+	Preview       string `yaml:"preview"` // Photo relative to the photo directory, optional
+}
+
+// DefaultEntrySpec returns a spec authored by the current user, which appends tracks and
+// gallery to the document.
+func DefaultEntrySpec() EntrySpec {
+	return EntrySpec{
+		Author:        os.Getenv("USER"),
+		AppendTracks:  true,
+		AppendGallery: true,
+	}
+}
+
+// ReadEntrySpec reads a spec in YAML or JSON format. Missing fields keep their defaults.
+func ReadEntrySpec(r io.Reader) (EntrySpec, error) {
+	spec := DefaultEntrySpec()
+
+	source, err := io.ReadAll(r)
+	if err != nil {
+		return spec, err
+	}
+
+	// YAML is a superset of JSON.
+	if err := yaml.Unmarshal(source, &spec); err != nil {
+		return spec, fmt.Errorf("parse spec: %w", err)
+	}
+
+	return spec, nil
+}
+
+// FrontMatter returns the front matter of the entry on the given date.
+func (spec EntrySpec) FrontMatter(date time.Time) data.FrontMatter {
+	tags := spec.Tags
+	if len(tags) == 0 {
+		tags = nil
+	}
+
+	return data.FrontMatter{
+		Title:    strings.TrimSpace(spec.Title),
+		Date:     data.YamlDate(date),
+		Author:   spec.Author,
+		Tags:     tags,
+		Abstract: strings.TrimSpace(spec.Abstract),
+	}
+}
+
+// IsToday reports whether the date of a spec denotes the current day.
+func IsToday(s string) bool {
+	return s == "today" || s == "heute"
+}
+
+// ParseDate parses the date of a spec.
+func ParseDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || IsToday(s) {
+		return time.Now(), nil
+	}
+
+	date, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return date, fmt.Errorf("invalid date '%s', want YYYY-MM-DD", s)
+	}
+
+	return date, nil
+}
+
+// NormalizeTitle turns a title into a part of a directory name of lower case letters and
+// digits separated by dashes.
+func NormalizeTitle(title string) string {
+	var b strings.Builder
+	lastDash := true
+	for _, r := range title {
+		if unicode.IsSpace(r) {
+			if !lastDash {
+				b.WriteString("-")
+				lastDash = true
+			}
+		} else if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(unicode.ToLower(r))
+			lastDash = false
+		}
+	}
+
+	return b.String()
+}
+
+// Entry locates an entry in the journal.
+type Entry struct {
+	Directory string
+	File      string // Markdown document
+}
+
+// EntryPaths returns the location of an entry in the journal.
+func EntryPaths(journalDirectory string, date time.Time, title string) Entry {
+	dateStr := dates.DateString(date)
+
+	directory := filepath.Join(
+		journalDirectory,
+		fmt.Sprint(date.Year()),
+		fmt.Sprintf("%s-%s", dateStr, NormalizeTitle(title)),
+	)
+
+	return Entry{
+		Directory: directory,
+		File:      filepath.Join(directory, fmt.Sprintf("doc_%s.md", dateStr)),
+	}
+}
+
+// StepError reports a failed step of adding files to a created entry.
+type StepError struct {
+	Step string // tracks, files, gallery or preview
+	Err  error
+}
+
+func (e *StepError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Step, e.Err)
+}
+
+func (e *StepError) Unwrap() error {
+	return e.Err
+}
+
+// CreateEntry creates the directory and markdown file of the entry described by the spec
+// and adds the tracks, extra files and photos of its photo directory. Once the markdown
+// file is written, the entry is returned even if adding files failed. Failed steps are
+// reported by StepErrors.
+func CreateEntry(journalDirectory string, spec EntrySpec) (Entry, error) {
+	date, err := ParseDate(spec.Date)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	if strings.TrimSpace(spec.Title) == "" {
+		return Entry{}, ErrNoTitle
+	}
+
+	if NormalizeTitle(spec.Title) == "" {
+		return Entry{}, ErrEmptyNormalizedTitle
+	}
+
+	entry := EntryPaths(journalDirectory, date, spec.Title)
+	if filesystem.Exists(entry.File) {
+		return Entry{}, fmt.Errorf("%w: %s", ErrEntryExists, entry.File)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteFrontMatter(&buf, spec.FrontMatter(date)); err != nil {
+		return Entry{}, err
+	}
+
+	if err := os.MkdirAll(entry.Directory, 0700); err != nil {
+		return Entry{}, err
+	}
+
+	if err := os.WriteFile(entry.File, buf.Bytes(), 0o666); err != nil {
+		return Entry{}, err
+	}
+
+	log.Printf("created entry '%s'", entry.File)
+
+	if spec.Photos == "" {
+		return entry, nil
+	}
+
+	var errs []error
+
+	tracks, err := CopyTracks(spec.Photos, entry.Directory)
+	if err == nil && spec.AppendTracks {
+		err = AppendTracks(entry.Directory, tracks)
+	}
+	if err != nil {
+		errs = append(errs, &StepError{"tracks", err})
+	}
+
+	err = CopyExtraFiles(spec.Photos, entry.Directory, config.CopyFileExtensions(), config.VideoExtensions())
+	if err != nil {
+		errs = append(errs, &StepError{"files", err})
+	}
+
+	opts := DefaultGalleryOptions()
+	opts.Sources = []string{spec.Photos}
+	opts.Directory = filepath.Join(entry.Directory, opts.Directory)
+
+	written, err := CreateGallery(opts)
+	if written > 0 && spec.AppendGallery {
+		err = errors.Join(err, AppendGallery(entry.Directory, opts.Directory))
+	}
+	if err != nil {
+		errs = append(errs, &StepError{"gallery", err})
+	}
+
+	if spec.Preview != "" {
+		previewOpts := DefaultPreviewOptions()
+		previewOpts.DocumentDirectory = entry.Directory
+		previewOpts.Source = spec.Preview
+
+		if !filepath.IsAbs(spec.Preview) {
+			previewOpts.Source = filepath.Join(spec.Photos, spec.Preview)
+		}
+
+		preview, err := CreatePreview(previewOpts)
+		if err == nil {
+			err = SetPreview(entry.Directory, preview)
+		}
+		if err != nil {
+			errs = append(errs, &StepError{"preview", err})
+		}
+	}
+
+	return entry, errors.Join(errs...)
+}
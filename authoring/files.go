@@ -0,0 +1,90 @@
+package authoring
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"slices"
+
+	"github.com/bgraf/rueckblick/data"
+	"github.com/bgraf/rueckblick/filesystem"
+	"github.com/bgraf/rueckblick/render"
+)
+
+// CopyTracks copies the track files of the input directory into the entry directory and
+// returns their names.
+func CopyTracks(inputDirectory string, entryDirectory string) ([]string, error) {
+	// NMEA tracks may have .txt extensions.
+	filePaths, err := filesystem.GatherFiles([]string{inputDirectory}, data.TrackExtensions())
+	if err != nil {
+		return nil, fmt.Errorf("scanning files: %w", err)
+	}
+
+	var names []string
+	for _, inPath := range filePaths {
+		name := filepath.Base(inPath)
+
+		if err := filesystem.Copy(inPath, filepath.Join(entryDirectory, name)); err != nil {
+			return names, err
+		}
+
+		names = append(names, name)
+	}
+
+	return names, nil
+}
+
+// AppendTracks appends a track element for each of the track files to the markdown
+// document in the entry directory.
+func AppendTracks(entryDirectory string, names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+
+	return EditDocument(entryDirectory, func(doc *Document) error {
+		for _, name := range names {
+			doc.Append(fmt.Sprintf("\n<%s track=\"%s\"></%s>\n", render.GPXTagName, name, render.GPXTagName))
+		}
+		return nil
+	})
+}
+
+// CopyExtraFiles copies the files of the input directory with one of the extensions into
+// the entry directory. Files with one of the video extensions are appended to the
+// markdown document as videos.
+func CopyExtraFiles(inputDirectory string, entryDirectory string, extensions []string, videoExtensions []string) error {
+	if len(extensions) == 0 {
+		return nil
+	}
+
+	filePaths, err := filesystem.GatherFiles([]string{inputDirectory}, extensions)
+	if err != nil {
+		return fmt.Errorf("scanning files: %w", err)
+	}
+
+	for _, inPath := range filePaths {
+		name := filepath.Base(inPath)
+
+		log.Printf("copying extra file '%s'\n", name)
+
+		if err := filesystem.Copy(inPath, filepath.Join(entryDirectory, name)); err != nil {
+			return err
+		}
+
+		if !slices.Contains(videoExtensions, filepath.Ext(name)) {
+			continue
+		}
+
+		err := EditDocument(entryDirectory, func(doc *Document) error {
+			doc.Append(fmt.Sprintf("\n<%s src=\"%s\"></%s>\n", render.VideoTagName, name, render.VideoTagName))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		log.Printf("added video '%s' to document\n", name)
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package authoring
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/bgraf/rueckblick/data"
+	"gopkg.in/yaml.v2"
+)
+
+// ErrNoSingleDocument reports a directory without or with multiple markdown documents.
+var ErrNoSingleDocument = errors.New("zero or multiple markdown files")
+
+// FindDocument returns the absolute path of the single markdown document in the
+// directory.
+func FindDocument(directory string) (string, error) {
+	directory, err := filepath.Abs(directory)
+	if err != nil {
+		return "", err
+	}
+
+	files, err := filepath.Glob(filepath.Join(directory, "*.md"))
+	if err != nil {
+		return "", fmt.Errorf("glob: %w", err)
+	}
+
+	if len(files) != 1 {
+		return "", fmt.Errorf("%w in '%s'", ErrNoSingleDocument, directory)
+	}
+
+	return files[0], nil
+}
+
+// WriteFrontMatter writes the front matter including its delimiters.
+func WriteFrontMatter(w io.Writer, fm data.FrontMatter) error {
+	if _, err := fmt.Fprintln(w, "---"); err != nil {
+		return err
+	}
+
+	if err := yaml.NewEncoder(w).Encode(fm); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintln(w, "---")
+	return err
+}
@@ -0,0 +1,179 @@
+package authoring
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/bgraf/rueckblick/config"
+	"github.com/bgraf/rueckblick/data"
+	"github.com/bgraf/rueckblick/filesystem"
+	"github.com/bgraf/rueckblick/images"
+	"github.com/bgraf/rueckblick/render"
+)
+
+var (
+	ErrNoImages          = errors.New("no images")
+	ErrUnsupportedFormat = errors.New("unsupported output format")
+)
+
+// GalleryOptions controls the creation of a gallery.
+type GalleryOptions struct {
+	Size        int // Maximum width or height of the images, no scaling if zero
+	JPEGQuality int
+	Format      string   // Output format extension, empty to keep the format
+	Directory   string   // Output directory
+	Sources     []string // Image files and directories searched for image files
+}
+
+// DefaultGalleryOptions returns the configured options without scaling.
+func DefaultGalleryOptions() GalleryOptions {
+	return GalleryOptions{
+		JPEGQuality: config.GalleryJPEGQuality(),
+		Format:      config.GalleryFormat(),
+		Directory:   config.DefaultPhotosDirectory(),
+	}
+}
+
+// CreateGallery copies, scales or converts the images of the sources into the gallery
+// directory and creates their thumbnails. It returns the number of images written, which
+// may be positive even if some images failed.
+func CreateGallery(opts GalleryOptions) (int, error) {
+	switch ImageExtension(".jpg", opts.Format) {
+	case ".jpg", ".png":
+	default:
+		return 0, fmt.Errorf("%w '%s'", ErrUnsupportedFormat, opts.Format)
+	}
+
+	filePaths, err := filesystem.GatherFiles(opts.Sources, []string{".jpeg", ".jpg", ".png"})
+	if err != nil {
+		return 0, fmt.Errorf("scanning files: %w", err)
+	} else if len(filePaths) == 0 {
+		return 0, ErrNoImages
+	}
+
+	thumbDirectory := filepath.Join(opts.Directory, config.DefaultThumbSubdirectory())
+	if err := os.MkdirAll(thumbDirectory, 0700); err != nil {
+		return 0, fmt.Errorf("create thumb directory: %w", err)
+	}
+
+	scaleOpts := images.ScaleOptions{
+		MaxSize:     opts.Size,
+		JPEGQuality: opts.JPEGQuality,
+	}
+
+	thumbOpts := images.ScaleOptions{
+		MaxSize:     config.DefaultThumbWidth(),
+		JPEGQuality: opts.JPEGQuality,
+	}
+
+	processImage := func(srcPath string) error {
+		srcExt := filepath.Ext(srcPath)
+		nameWithoutExt := strings.TrimSuffix(filepath.Base(srcPath), srcExt)
+		dstExt := ImageExtension(srcExt, opts.Format)
+		dstPath := filepath.Join(opts.Directory, nameWithoutExt+dstExt)
+
+		// Images are only re-encoded if they are scaled or converted.
+		if opts.Size > 0 || ImageExtension(srcExt, "") != dstExt {
+			if err := images.ScaleFile(srcPath, dstPath, scaleOpts); err != nil {
+				return err
+			}
+			log.Printf("scaled: %s => %s\n", srcPath, dstPath)
+		} else {
+			if err := filesystem.Copy(srcPath, dstPath); err != nil {
+				return fmt.Errorf("copy '%s': %w", srcPath, err)
+			}
+			log.Printf("copied: %s => %s\n", srcPath, dstPath)
+		}
+
+		thumbPath := data.ThumbnailPath(dstPath)
+		if err := images.ScaleFile(dstPath, thumbPath, thumbOpts); err != nil {
+			return fmt.Errorf("thumbnail: %w", err)
+		}
+		log.Printf("Thumb: created %s", thumbPath)
+
+		return nil
+	}
+
+	var (
+		wg       sync.WaitGroup
+		errMutex sync.Mutex
+		errs     []error
+	)
+
+	srcFiles := make(chan string)
+
+	for range runtime.NumCPU() {
+		wg.Add(1)
+		go func(srcFiles <-chan string) {
+			defer wg.Done()
+			for path := range srcFiles {
+				if err := processImage(path); err != nil {
+					errMutex.Lock()
+					errs = append(errs, fmt.Errorf("%s: %w", path, err))
+					errMutex.Unlock()
+				}
+			}
+		}(srcFiles)
+	}
+
+	for _, path := range filePaths {
+		srcFiles <- path
+	}
+
+	close(srcFiles)
+
+	wg.Wait()
+
+	written := len(filePaths) - len(errs)
+
+	if len(errs) > 0 {
+		return written, fmt.Errorf("%d of %d images failed: %w", len(errs), len(filePaths), errors.Join(errs...))
+	}
+
+	return written, nil
+}
+
+// ImageExtension returns the extension of a gallery image. If a format is given, it takes
+// precedence over the source extension.
+func ImageExtension(ext string, format string) string {
+	if len(format) > 0 {
+		ext = "." + strings.TrimPrefix(format, ".")
+	}
+
+	ext = strings.ToLower(ext)
+	if ext == ".jpeg" {
+		ext = ".jpg"
+	}
+	return ext
+}
+
+// AppendGallery appends a gallery element for the gallery directory to the markdown
+// document in the document directory.
+func AppendGallery(documentDirectory string, galleryDirectory string) error {
+	var err error
+
+	// Make gallery path relative to document directory
+	galleryRelPath := galleryDirectory
+	if filepath.IsAbs(galleryDirectory) {
+		galleryRelPath, err = filepath.Rel(documentDirectory, galleryDirectory)
+		if err != nil {
+			return fmt.Errorf("obtain relative path: %w", err)
+		}
+	}
+
+	return EditDocument(documentDirectory, func(doc *Document) error {
+		dirAttr := ""
+		if galleryRelPath != config.DefaultPhotosDirectory() {
+			dirAttr = fmt.Sprintf(`%s="%s"`, render.GalleryTagDirectoryAttrName, galleryRelPath)
+		}
+
+		doc.Append(fmt.Sprintf("\n<%s %s></%s>\n", render.GalleryTagName, dirAttr, render.GalleryTagName))
+		return nil
+	})
+}
@@ -0,0 +1,83 @@
+package authoring
+
+import (
+	"fmt"
+	"image"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+
+	"github.com/bgraf/rueckblick/config"
+	"github.com/disintegration/imaging"
+)
+
+// PreviewOptions controls the creation of a preview image.
+type PreviewOptions struct {
+	Source            string // Image the preview is cut from
+	Target            string // Preview image, relative to the document directory if not absolute
+	DocumentDirectory string
+	Size              int // Width and height of the preview
+}
+
+// DefaultPreviewOptions returns the configured size and file name of preview images.
+func DefaultPreviewOptions() PreviewOptions {
+	return PreviewOptions{
+		Size:   config.DefaultPreviewWidth(),
+		Target: config.DefaultPreviewFilename(),
+	}
+}
+
+// CreatePreview cuts a square preview image from the center of the source image and
+// returns the path of the preview.
+func CreatePreview(opts PreviewOptions) (string, error) {
+	f, err := os.Open(opts.Source)
+	if err != nil {
+		return "", err
+	}
+
+	defer func() { _ = f.Close() }()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return "", fmt.Errorf("image decode failed: %w", err)
+	}
+
+	previewImg := imaging.Fill(img, opts.Size, opts.Size, imaging.Center, imaging.Lanczos)
+
+	target := opts.Target
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(opts.DocumentDirectory, target)
+	}
+
+	fOut, err := os.Create(target)
+	if err != nil {
+		return "", fmt.Errorf("could not create output image: %w", err)
+	}
+
+	defer func() { _ = fOut.Close() }()
+
+	jpegOpts := jpeg.Options{Quality: config.DefaultPreviewJPEGQuality()}
+	if err := jpeg.Encode(fOut, previewImg, &jpegOpts); err != nil {
+		return "", fmt.Errorf("saving preview image failed: %w", err)
+	}
+
+	return target, nil
+}
+
+// SetPreview sets the preview of the markdown document in the document directory to the
+// preview image.
+func SetPreview(documentDirectory string, previewPath string) error {
+	return EditDocument(documentDirectory, func(doc *Document) error {
+		previewPath, err := filepath.Abs(previewPath)
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(filepath.Dir(doc.Path), previewPath)
+		if err != nil {
+			return err
+		}
+
+		return doc.Set("preview", rel)
+	})
+}
@@ -0,0 +1,158 @@
+package building
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bgraf/rueckblick/data"
+	"github.com/bgraf/rueckblick/filesystem"
+	"github.com/bgraf/rueckblick/metacache"
+)
+
+// Name of the build subdirectory containing all installed assets.
+const assetDirectory = "assets"
+
+// assetTree installs referenced resources (images, thumbnails, previews, videos, tracks)
+// into a content-addressed directory tree below the build directory, so the build output
+// does not depend on the location of the journal.
+type assetTree struct {
+	buildDirectory string
+
+	mu        sync.Mutex
+	byPath    map[string]string
+	bySources map[string]map[string]struct{} // Source paths referenced by document paths
+}
+
+func newAssetTree(buildDirectory string) *assetTree {
+	return &assetTree{
+		buildDirectory: buildDirectory,
+		byPath:         make(map[string]string),
+		bySources:      make(map[string]map[string]struct{}),
+	}
+}
+
+// Install links or copies the file at srcPath into the asset tree and returns its URL
+// relative to the build directory.
+func (a *assetTree) Install(srcPath string) (string, error) {
+	a.mu.Lock()
+	uri, ok := a.byPath[srcPath]
+	a.mu.Unlock()
+
+	if ok {
+		return uri, nil
+	}
+
+	hash, err := hashFile(srcPath)
+	if err != nil {
+		return "", err
+	}
+
+	rel := path.Join(assetDirectory, hash[:2], hash+strings.ToLower(filepath.Ext(srcPath)))
+	dst := filepath.Join(a.buildDirectory, filepath.FromSlash(rel))
+
+	if !filesystem.Exists(dst) {
+		if err := filesystem.CreateDirectoryIfNotExists(filepath.Dir(dst)); err != nil {
+			return "", fmt.Errorf("create asset directory: %w", err)
+		}
+
+		// Another worker may install the same file concurrently, thus only fail if the
+		// asset is still missing afterwards.
+		if err := filesystem.LinkOrCopy(srcPath, dst); err != nil && !filesystem.Exists(dst) {
+			return "", fmt.Errorf("install asset '%s': %w", srcPath, err)
+		}
+	}
+
+	uri = "./" + rel
+
+	a.mu.Lock()
+	a.byPath[srcPath] = uri
+	a.mu.Unlock()
+
+	return uri, nil
+}
+
+// RenderImagePath implements `data.StoreOptions.RenderImagePath` by installing the source
+// file as an asset. Paths not denoting regular files are rejected.
+func (a *assetTree) RenderImagePath(doc *data.Document, srcPath string) (data.Resource, bool) {
+	fi, err := os.Stat(srcPath)
+	if err != nil || !fi.Mode().IsRegular() {
+		return data.Resource{}, false
+	}
+
+	uri, err := a.Install(srcPath)
+	if err != nil {
+		log.Printf("could not install asset: %s", err)
+		return data.Resource{}, false
+	}
+
+	a.mu.Lock()
+	if a.bySources[doc.Path] == nil {
+		a.bySources[doc.Path] = make(map[string]struct{})
+	}
+	a.bySources[doc.Path][srcPath] = struct{}{}
+	a.mu.Unlock()
+
+	return data.Resource{URI: uri}, true
+}
+
+// Sources returns the sorted paths of all files installed on behalf of the document.
+func (a *assetTree) Sources(doc *data.Document) []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var sources []string
+	for src := range a.bySources[doc.Path] {
+		sources = append(sources, src)
+	}
+
+	sort.Strings(sources)
+
+	return sources
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+
+	defer func() { _ = f.Close() }()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("hash file '%s': %w", path, err)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))[:32], nil
+}
+
+// OpenStore loads the journal store such that all resources referenced by documents
+// are installed into the asset tree of the given build directory. Image metadata is read
+// through the given cache.
+func OpenStore(journalDirectory, buildDirectory string, metadata *metacache.Cache) (*data.Store, error) {
+	store, _, err := openStore(journalDirectory, buildDirectory, metadata)
+	return store, err
+}
+
+func openStore(journalDirectory, buildDirectory string, metadata *metacache.Cache) (*data.Store, *assetTree, error) {
+	assets := newAssetTree(buildDirectory)
+
+	store, err := data.NewDefaultStoreWithOptions(
+		journalDirectory,
+		&data.StoreOptions{
+			RenderImagePath: assets.RenderImagePath,
+			ReadEXIF:        metadata.EXIF,
+		},
+	)
+
+	return store, assets, err
+}
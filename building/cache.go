@@ -5,22 +5,15 @@ import (
 	"os"
 	"path/filepath"
 	"time"
-
-	"github.com/bgraf/rueckblick/data"
 )
 
 const cacheFileName = "cache.json"
 
+// buildCache is persisted in the build directory and describes the previous build.
 type buildCache struct {
-	Documents []cacheDocument `json:"documents"`
-}
-
-type cacheDocument struct {
-	Title      string     `json:"title"`
-	Tags       []data.Tag `json:"tags"`
-	Date       jsonDate   `json:"date"`
-	Path       string     `json:"path"`
-	OutputPath string     `json:"outputPath"`
+	Templates string                  `json:"templates"` // Hash of the templates
+	Static    string                  `json:"static"`    // Hash of the installed static files
+	Outputs   map[string]outputRecord `json:"outputs"`
 }
 
 type jsonDate time.Time
@@ -54,28 +47,8 @@ func readBuildCache(buildDirectory string) (cache buildCache, err error) {
 	return
 }
 
-func makeBuildCache(state *buildState) buildCache {
-	store := state.store
-
-	cache := buildCache{}
-
-	for _, doc := range store.Documents {
-		cdoc := cacheDocument{
-			Title:      doc.Title,
-			Tags:       doc.Tags,
-			Date:       jsonDate(doc.Date),
-			Path:       doc.Path,
-			OutputPath: state.filenamer.EntryFile(doc),
-		}
-
-		cache.Documents = append(cache.Documents, cdoc)
-	}
-
-	return cache
-}
-
-func writeBuildCache(state *buildState) error {
-	cache := makeBuildCache(state)
+func writeBuildCache(state *buildState, cache buildCache) error {
+	cache.Outputs = state.graph.next
 
 	jsonBytes, err := json.Marshal(cache)
 	if err != nil {
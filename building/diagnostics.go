@@ -0,0 +1,75 @@
+package building
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// BuildError summarizes all errors that occurred during a build.
+type BuildError struct {
+	Errors []error
+}
+
+func (e *BuildError) Error() string {
+	var b strings.Builder
+
+	if len(e.Errors) == 1 {
+		b.WriteString("build failed with 1 error:")
+	} else {
+		fmt.Fprintf(&b, "build failed with %d errors:", len(e.Errors))
+	}
+
+	for _, err := range e.Errors {
+		b.WriteString("\n  ")
+		b.WriteString(err.Error())
+	}
+
+	return b.String()
+}
+
+func (e *BuildError) Unwrap() []error {
+	return e.Errors
+}
+
+// diagnostics collects the errors of documents failing to build. It is safe for
+// concurrent use.
+type diagnostics struct {
+	mu   sync.Mutex
+	errs []error
+}
+
+func (d *diagnostics) Report(err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.errs = append(d.errs, err)
+}
+
+func (d *diagnostics) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return len(d.errs)
+}
+
+// Err returns a *BuildError of all reported errors ordered by their messages, or nil if
+// no error was reported.
+func (d *diagnostics) Err() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.errs) == 0 {
+		return nil
+	}
+
+	errs := make([]error, len(d.errs))
+	copy(errs, d.errs)
+
+	sort.Slice(errs, func(i, j int) bool {
+		return errs[i].Error() < errs[j].Error()
+	})
+
+	return &BuildError{Errors: errs}
+}
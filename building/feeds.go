@@ -0,0 +1,370 @@
+package building
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/bgraf/rueckblick/config"
+	"github.com/bgraf/rueckblick/data"
+	"github.com/bgraf/rueckblick/render"
+)
+
+// Name of the build subdirectory storing the rendered body fragments of all entries, so
+// feeds can include the bodies of documents which are not rerendered.
+const fragmentDirectory = "fragments"
+
+const (
+	atomFeedFileName = "atom.xml"
+	jsonFeedFileName = "feed.json"
+)
+
+func (f Filenamer) TagFeedFile(tag data.Tag) string {
+	return strings.TrimSuffix(f.TagFile(tag), ".html") + ".xml"
+}
+
+func fragmentPath(state *buildState, doc *data.Document) string {
+	return filepath.Join(fragmentDirectory, state.filenamer.EntryFile(doc))
+}
+
+type feedItem struct {
+	Title     string
+	URL       string
+	Date      time.Time
+	Abstract  string
+	Preview   string
+	Tags      []string
+	HTML      string
+	MediaType string
+}
+
+type feedWriter struct {
+	state   *buildState
+	baseURL *url.URL
+	items   map[string]feedItem
+}
+
+// writeFeeds writes an Atom and a JSON feed of the latest documents and one Atom feed per
+// tag. Feeds are skipped if no base URL is configured.
+func writeFeeds(state *buildState) error {
+	if !config.HasFeedBaseURL() {
+		log.Printf("no %s configured, skipping feeds", config.KeyFeedBaseURL)
+		return nil
+	}
+
+	baseURL, err := url.Parse(config.FeedBaseURL())
+	if err != nil || !baseURL.IsAbs() {
+		return fmt.Errorf("invalid feed base URL '%s'", config.FeedBaseURL())
+	}
+
+	if !strings.HasSuffix(baseURL.Path, "/") {
+		baseURL.Path += "/"
+	}
+
+	w := &feedWriter{
+		state:   state,
+		baseURL: baseURL,
+		items:   make(map[string]feedItem),
+	}
+
+	title := config.FeedTitle()
+	latest := w.latest(state.store.Documents)
+
+	if inputs := feedInputs(latest); state.graph.Stale("feeds", inputs) {
+		if err := w.writeAtom(atomFeedFileName, title, latest); err != nil {
+			return err
+		}
+
+		if err := w.writeJSON(jsonFeedFileName, title, latest); err != nil {
+			return err
+		}
+
+		state.graph.Record("feeds", []string{atomFeedFileName, jsonFeedFileName}, inputs)
+	}
+
+	for _, tag := range state.store.Tags() {
+		docs := w.latest(state.store.DocumentsByTagName(tag.Raw))
+
+		output := "tag-feed:" + tag.Raw
+		inputs := feedInputs(docs)
+		if !state.graph.Stale(output, inputs) {
+			continue
+		}
+
+		fileName := state.filenamer.TagFeedFile(tag)
+		tagTitle := fmt.Sprintf("%s: %s", title, tag.String())
+
+		if err := w.writeAtom(fileName, tagTitle, docs); err != nil {
+			return err
+		}
+
+		state.graph.Record(output, []string{fileName}, inputs)
+	}
+
+	return nil
+}
+
+// feedInputs returns the inputs of a feed containing the given documents.
+func feedInputs(docs []*data.Document) []string {
+	inputs := []string{"feed-config"}
+	for _, doc := range docs {
+		inputs = append(inputs, metaInput(doc), documentInput(doc))
+	}
+
+	return inputs
+}
+
+func (w *feedWriter) latest(docs []*data.Document) []*data.Document {
+	n := config.FeedEntries()
+	if len(docs) > n {
+		return docs[:n]
+	}
+
+	return docs
+}
+
+func (w *feedWriter) absoluteURL(ref string) string {
+	u, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+
+	return w.baseURL.ResolveReference(u).String()
+}
+
+// item assembles the feed data of the document. The body is taken from the stored
+// fragment of the latest rendering.
+func (w *feedWriter) item(doc *data.Document) feedItem {
+	if item, ok := w.items[doc.Path]; ok {
+		return item
+	}
+
+	item := feedItem{
+		Title:    doc.Title,
+		URL:      w.absoluteURL(render.EntryURL(w.state.filenamer, doc)),
+		Date:     doc.Date,
+		Abstract: doc.Abstract,
+	}
+
+	if doc.HasPreview() && len(doc.PreviewResource.URI) > 0 {
+		item.Preview = w.absoluteURL(doc.PreviewResource.URI)
+		item.MediaType = "image/jpeg"
+		if strings.EqualFold(filepath.Ext(doc.Preview), ".png") {
+			item.MediaType = "image/png"
+		}
+	}
+
+	for _, tag := range doc.Tags {
+		item.Tags = append(item.Tags, tag.String())
+	}
+
+	fragment, err := os.ReadFile(filepath.Join(w.state.BuildDirectory, fragmentPath(w.state, doc)))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("could not read fragment of '%s': %s", doc.Path, err)
+		}
+	} else if item.HTML, err = w.feedHTML(string(fragment)); err != nil {
+		log.Printf("could not prepare fragment of '%s': %s", doc.Path, err)
+	}
+
+	if item.Preview != "" {
+		item.HTML = fmt.Sprintf(`<p><img src="%s"></p>`, item.Preview) + item.HTML
+	}
+
+	w.items[doc.Path] = item
+
+	return item
+}
+
+// feedHTML removes scripts and maps from the fragment, because feed readers do not execute
+// scripts, and makes all references absolute.
+func (w *feedWriter) feedHTML(fragment string) (string, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(fragment))
+	if err != nil {
+		return "", err
+	}
+
+	doc.Find("script,div.gpx-map").Remove()
+
+	for _, attr := range []string{"src", "href"} {
+		doc.Find("[" + attr + "]").Each(func(i int, s *goquery.Selection) {
+			s.SetAttr(attr, w.absoluteURL(s.AttrOr(attr, "")))
+		})
+	}
+
+	return doc.Find("body").Html()
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr,omitempty"`
+	Body string `xml:",chardata"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Links      []atomLink     `xml:"link"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Summary    *atomText      `xml:"summary,omitempty"`
+	Content    *atomText      `xml:"content,omitempty"`
+	Categories []atomCategory `xml:"category"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+func (w *feedWriter) writeAtom(fileName string, title string, docs []*data.Document) error {
+	feedURL := w.absoluteURL(fileName)
+
+	feed := atomFeed{
+		Title:   title,
+		ID:      feedURL,
+		Updated: feedTime(docs),
+		Links: []atomLink{
+			{Href: feedURL, Rel: "self", Type: "application/atom+xml"},
+			{Href: w.absoluteURL("index.html"), Rel: "alternate", Type: "text/html"},
+		},
+	}
+
+	for _, doc := range docs {
+		item := w.item(doc)
+
+		entry := atomEntry{
+			Title:     item.Title,
+			ID:        item.URL,
+			Links:     []atomLink{{Href: item.URL, Rel: "alternate", Type: "text/html"}},
+			Published: item.Date.Format(time.RFC3339),
+			Updated:   item.Date.Format(time.RFC3339),
+		}
+
+		if item.Preview != "" {
+			entry.Links = append(entry.Links, atomLink{Href: item.Preview, Rel: "enclosure", Type: item.MediaType})
+		}
+
+		if item.Abstract != "" {
+			entry.Summary = &atomText{Type: "text", Body: item.Abstract}
+		}
+
+		if item.HTML != "" {
+			entry.Content = &atomText{Type: "html", Body: item.HTML}
+		}
+
+		for _, tag := range item.Tags {
+			entry.Categories = append(entry.Categories, atomCategory{Term: tag})
+		}
+
+		feed.Entries = append(feed.Entries, entry)
+	}
+
+	var buf bytes.Buffer
+	_, _ = buf.WriteString(xml.Header)
+
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(feed); err != nil {
+		return fmt.Errorf("could not encode feed: %w", err)
+	}
+
+	if err := w.state.WriteFile(fileName, buf.Bytes()); err != nil {
+		return fmt.Errorf("could not write feed file: %w", err)
+	}
+
+	return nil
+}
+
+type jsonFeedItem struct {
+	ID            string   `json:"id"`
+	URL           string   `json:"url"`
+	Title         string   `json:"title"`
+	ContentHTML   string   `json:"content_html,omitempty"`
+	ContentText   string   `json:"content_text,omitempty"`
+	Summary       string   `json:"summary,omitempty"`
+	Image         string   `json:"image,omitempty"`
+	DatePublished string   `json:"date_published"`
+	Tags          []string `json:"tags,omitempty"`
+}
+
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	FeedURL     string         `json:"feed_url"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+func (w *feedWriter) writeJSON(fileName string, title string, docs []*data.Document) error {
+	feed := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       title,
+		HomePageURL: w.absoluteURL("index.html"),
+		FeedURL:     w.absoluteURL(fileName),
+		Items:       []jsonFeedItem{},
+	}
+
+	for _, doc := range docs {
+		item := w.item(doc)
+
+		jsonItem := jsonFeedItem{
+			ID:            item.URL,
+			URL:           item.URL,
+			Title:         item.Title,
+			ContentHTML:   item.HTML,
+			Summary:       item.Abstract,
+			Image:         item.Preview,
+			DatePublished: item.Date.Format(time.RFC3339),
+			Tags:          item.Tags,
+		}
+
+		// JSON Feed requires either HTML or text content.
+		if jsonItem.ContentHTML == "" {
+			jsonItem.ContentText = item.Abstract
+		}
+
+		feed.Items = append(feed.Items, jsonItem)
+	}
+
+	payloadBytes, err := json.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not encode feed: %w", err)
+	}
+
+	if err := w.state.WriteFile(fileName, payloadBytes); err != nil {
+		return fmt.Errorf("could not write feed file: %w", err)
+	}
+
+	return nil
+}
+
+func feedTime(docs []*data.Document) string {
+	if len(docs) == 0 {
+		return time.Now().Format(time.RFC3339)
+	}
+
+	return docs[0].Date.Format(time.RFC3339)
+}
@@ -0,0 +1,188 @@
+package building
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"math"
+	"os"
+
+	"github.com/bgraf/rueckblick/config"
+	"github.com/bgraf/rueckblick/data"
+	"github.com/bgraf/rueckblick/geotrack"
+	"github.com/bgraf/rueckblick/render"
+)
+
+// GlobalMapFileName is the file of the global map in the build directory.
+const GlobalMapFileName = "globmap.html"
+
+type globalMapData struct {
+	Entries []globalMapEntry `json:"entries"`
+	Tracks  bool             `json:"tracks"`
+	Heatmap bool             `json:"heatmap"`
+}
+
+type globalMapEntry struct {
+	URI     string             `json:"uri"`
+	Preview string             `json:"preview"`
+	Title   string             `json:"title"`
+	Year    int                `json:"year"`
+	Tags    []string           `json:"tags"`
+	Marker  *geotrack.GPXPoint `json:"marker,omitempty"`
+	Tracks  [][][2]float64     `json:"tracks,omitempty"`
+	Heat    [][3]float64       `json:"heat,omitempty"`
+}
+
+// collectTrackFiles finds the track files shown on the maps of each document. It must be
+// called before any document is rendered, which replaces the map tags.
+func collectTrackFiles(state *buildState) map[string][]string {
+	trackFiles := make(map[string][]string)
+
+	for _, doc := range state.store.Documents {
+		for _, m := range render.GeoMaps(doc) {
+			trackFiles[doc.Path] = append(trackFiles[doc.Path], m.GPXPath)
+		}
+	}
+
+	return trackFiles
+}
+
+func tracksInput(doc *data.Document) string { return "tracks:" + doc.Path }
+
+// writeGlobalMap writes the map of all entries with tracks, marking those leading farther
+// from home than the map threshold. The map requires the home coordinates.
+func writeGlobalMap(state *buildState) error {
+	if !config.HasHomeCoords() {
+		log.Printf("no home coordinates configured, skipping global map")
+		return nil
+	}
+
+	inputs := []string{"globalmap-config"}
+	for _, doc := range state.store.Documents {
+		if len(state.trackFiles[doc.Path]) > 0 {
+			inputs = append(inputs, metaInput(doc), tracksInput(doc))
+		}
+	}
+
+	if !state.graph.Stale("globalmap", inputs) {
+		return nil
+	}
+
+	home := config.HomeCoords()
+	threshold := config.MapThreshold()
+	tolerance := config.MapSimplify()
+
+	payload := globalMapData{
+		Entries: []globalMapEntry{},
+		Tracks:  config.GlobalMapTracks(),
+		Heatmap: config.GlobalMapHeatmap(),
+	}
+
+	for _, doc := range state.store.Documents {
+		trackFiles := state.trackFiles[doc.Path]
+		if len(trackFiles) == 0 {
+			continue
+		}
+
+		entry := globalMapEntry{
+			URI:     render.EntryURL(state.filenamer, doc),
+			Preview: render.PreviewURL(state.filenamer, doc),
+			Title:   doc.Title,
+			Year:    doc.Date.Year(),
+			Tags:    []string{},
+		}
+
+		for _, tag := range doc.Tags {
+			entry.Tags = append(entry.Tags, tag.Raw)
+		}
+
+		maxDist := 0.0
+		var maxPoint geotrack.GPXPoint
+
+		for _, trackFile := range trackFiles {
+			summary, err := state.metadata.TrackSummary(trackFile)
+			if err != nil {
+				log.Printf("skipping track '%s' on global map: %s", trackFile, err)
+				continue
+			}
+
+			p, dkm := summary.FarthestFrom(home.Lat, home.Lon)
+			if dkm > maxDist {
+				maxDist = dkm
+				maxPoint = p
+			}
+
+			if payload.Tracks {
+				for _, path := range summary.Path {
+					entry.Tracks = append(entry.Tracks, simplifyPath(path, tolerance))
+				}
+			}
+
+			if payload.Heatmap {
+				entry.Heat = append(entry.Heat, summary.Cells...)
+			}
+		}
+
+		if maxDist >= threshold {
+			entry.Marker = &maxPoint
+		}
+
+		if entry.Marker != nil || len(entry.Tracks) > 0 || len(entry.Heat) > 0 {
+			payload.Entries = append(payload.Entries, entry)
+		}
+	}
+
+	var buf bytes.Buffer
+	err := state.templates.ExecuteTemplate(&buf, "globmap.html", map[string]any{
+		"MapData": payload,
+	})
+	if err != nil {
+		return fmt.Errorf("could not execute template: %w", err)
+	}
+
+	if err := state.WriteFile(GlobalMapFileName, buf.Bytes()); err != nil {
+		return fmt.Errorf("could not write global map file: %w", err)
+	}
+
+	state.graph.Record("globalmap", []string{GlobalMapFileName}, inputs)
+
+	log.Printf("written global map with %d entries", len(payload.Entries))
+
+	return nil
+}
+
+// fingerprintTracks fingerprints the track files of a document by their sizes and
+// modification times.
+func fingerprintTracks(trackFiles []string) (string, error) {
+	var stats []any
+
+	for _, trackFile := range trackFiles {
+		fi, err := os.Stat(trackFile)
+		if err != nil {
+			stats = append(stats, trackFile, nil)
+			continue
+		}
+
+		stats = append(stats, trackFile, fi.Size(), fi.ModTime().UnixNano())
+	}
+
+	return hashValue(stats...)
+}
+
+// simplifyPath simplifies a path of latitude, longitude pairs by the tolerance in meters.
+// Coordinates are rounded to about a meter, which keeps the page small.
+func simplifyPath(path [][2]float64, tolerance float64) [][2]float64 {
+	points := make([]geotrack.GPXPoint, len(path))
+	for i, p := range path {
+		points[i] = geotrack.GPXPoint{Lat: p[0], Lon: p[1]}
+	}
+
+	points = geotrack.Simplify(points, tolerance)
+
+	simplified := make([][2]float64, len(points))
+	for i, p := range points {
+		simplified[i] = [2]float64{math.Round(p.Lat*1e5) / 1e5, math.Round(p.Lon*1e5) / 1e5}
+	}
+
+	return simplified
+}
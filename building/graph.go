@@ -0,0 +1,360 @@
+package building
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/bgraf/rueckblick/config"
+	"github.com/bgraf/rueckblick/data"
+	"github.com/bgraf/rueckblick/filesystem"
+)
+
+// outputRecord describes one output of the build: the files it consists of and the
+// fingerprints of the inputs it was made from.
+type outputRecord struct {
+	Files  []string          `json:"files"`
+	Inputs map[string]string `json:"inputs"`
+}
+
+// depGraph decides which outputs need to be regenerated by comparing the fingerprints of
+// their inputs recorded by the previous build to the current ones.
+//
+// Inputs are identified by keys of the form `kind:argument`, e.g., `document:<path>`
+// for the content of a document including all files in its directory, `meta:<path>` for
+// the information about a document shown on other pages, `tracks:<path>` for the track
+// files shown on its maps, or `asset:<path>` for a referenced file.
+type depGraph struct {
+	state       *buildState
+	fullRebuild bool
+	previous    map[string]outputRecord
+
+	mu           sync.Mutex
+	next         map[string]outputRecord
+	declared     map[string]bool
+	fingerprints map[string]string
+	written      int
+}
+
+func newDepGraph(state *buildState, previous map[string]outputRecord, fullRebuild bool) *depGraph {
+	if previous == nil {
+		previous = make(map[string]outputRecord)
+	}
+
+	return &depGraph{
+		state:        state,
+		fullRebuild:  fullRebuild,
+		previous:     previous,
+		next:         make(map[string]outputRecord),
+		declared:     make(map[string]bool),
+		fingerprints: make(map[string]string),
+	}
+}
+
+// Stale declares the output with its statically known inputs and reports whether it
+// needs to be regenerated. Outputs that are up to date keep their previous record.
+func (g *depGraph) Stale(output string, inputs []string) bool {
+	g.mu.Lock()
+	g.declared[output] = true
+	g.mu.Unlock()
+
+	if g.fullRebuild {
+		return true
+	}
+
+	prev, ok := g.previous[output]
+	if !ok {
+		return true
+	}
+
+	for _, key := range inputs {
+		if _, ok := prev.Inputs[key]; !ok {
+			return true
+		}
+	}
+
+	// Also check inputs only discovered while generating the output, such as assets.
+	for key, fingerprint := range prev.Inputs {
+		if g.fingerprint(key) != fingerprint {
+			return true
+		}
+	}
+
+	for _, file := range prev.Files {
+		if !filesystem.Exists(filepath.Join(g.state.BuildDirectory, file)) {
+			return true
+		}
+	}
+
+	g.mu.Lock()
+	g.next[output] = prev
+	g.mu.Unlock()
+
+	return false
+}
+
+// Record stores the files and inputs of a regenerated output.
+func (g *depGraph) Record(output string, files []string, inputs []string) {
+	record := outputRecord{
+		Files:  files,
+		Inputs: make(map[string]string, len(inputs)),
+	}
+
+	for _, key := range inputs {
+		record.Inputs[key] = g.fingerprint(key)
+	}
+
+	g.mu.Lock()
+	g.next[output] = record
+	g.written++
+	g.mu.Unlock()
+}
+
+// Written returns the number of regenerated outputs.
+func (g *depGraph) Written() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.written
+}
+
+// RemoveObsolete deletes the files of outputs which were not declared by this build, e.g.,
+// those of deleted documents and tags, as well as files an output no longer consists of.
+func (g *depGraph) RemoveObsolete() error {
+	current := make(map[string]bool)
+	for _, record := range g.next {
+		for _, file := range record.Files {
+			current[file] = true
+		}
+	}
+
+	for output, record := range g.previous {
+		if _, ok := g.next[output]; !ok && g.declared[output] {
+			// Regeneration failed, keep the previous files.
+			continue
+		}
+
+		for _, file := range record.Files {
+			if current[file] {
+				continue
+			}
+
+			err := os.Remove(filepath.Join(g.state.BuildDirectory, file))
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("could not remove obsolete output: %w", err)
+			}
+
+			log.Printf("removed obsolete file '%s'", file)
+		}
+	}
+
+	return nil
+}
+
+func (g *depGraph) fingerprint(key string) string {
+	g.mu.Lock()
+	fingerprint, ok := g.fingerprints[key]
+	g.mu.Unlock()
+
+	if ok {
+		return fingerprint
+	}
+
+	fingerprint, err := g.computeFingerprint(key)
+	if err != nil {
+		// An input that cannot be fingerprinted is considered changed.
+		log.Printf("could not fingerprint input '%s': %s", key, err)
+		return ""
+	}
+
+	g.mu.Lock()
+	g.fingerprints[key] = fingerprint
+	g.mu.Unlock()
+
+	return fingerprint
+}
+
+func (g *depGraph) computeFingerprint(key string) (string, error) {
+	state := g.state
+	kind, arg, _ := strings.Cut(key, ":")
+
+	document := func() *data.Document {
+		if i, ok := state.indexbyPath[arg]; ok {
+			return state.store.Documents[i]
+		}
+		return nil
+	}
+
+	switch kind {
+	case "document":
+		doc := document()
+		if doc == nil {
+			return "", nil
+		}
+
+		modTime, err := filesystem.FullSubtreeModifiedDate(doc.DocumentDirectory())
+		if err != nil {
+			return "", err
+		}
+
+		hash, err := hashFile(doc.Path)
+		if err != nil {
+			return "", err
+		}
+
+		return hashValue(hash, modTime.UnixNano())
+
+	case "meta":
+		doc := document()
+		if doc == nil {
+			return "", nil
+		}
+
+		return hashValue(
+			doc.Title,
+			doc.Date,
+			doc.TimeDisplay(),
+			doc.Tags,
+			doc.Periods,
+			doc.Abstract,
+			doc.PreviewResource.URI,
+			state.filenamer.EntryFile(doc),
+		)
+
+	case "neighbors":
+		doc := document()
+		if doc == nil {
+			return "", nil
+		}
+
+		i := state.Index(doc)
+
+		var neighbors []string
+		if i > 0 {
+			neighbors = append(neighbors, state.store.Documents[i-1].Path)
+		}
+		neighbors = append(neighbors, "|")
+		if i+1 < len(state.store.Documents) {
+			neighbors = append(neighbors, state.store.Documents[i+1].Path)
+		}
+
+		return hashValue(neighbors)
+
+	case "asset":
+		fi, err := os.Stat(arg)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return "", nil
+			}
+			return "", err
+		}
+
+		return hashValue(fi.Size(), fi.ModTime().UnixNano())
+
+	case "years":
+		years := make(map[int]bool)
+		for _, doc := range state.store.Documents {
+			years[doc.Date.Year()] = true
+		}
+
+		return hashValue(years)
+
+	case "calendar-range":
+		docs := state.store.Documents
+		if len(docs) == 0 {
+			return "", nil
+		}
+
+		return hashValue(docs[0].Date, docs[len(docs)-1].Date)
+
+	case "periods":
+		return hashValue(state.store.Periods)
+
+	case "tags":
+		return hashValue(state.store.Tags())
+
+	case "feed-config":
+		return hashValue(config.FeedBaseURL(), config.FeedTitle(), config.FeedEntries())
+
+	case "tracks":
+		doc := document()
+		if doc == nil {
+			return "", nil
+		}
+
+		return fingerprintTracks(state.trackFiles[doc.Path])
+
+	case "globalmap-config":
+		return hashValue(
+			config.HomeCoords(),
+			config.MapThreshold(),
+			config.GlobalMapTracks(),
+			config.GlobalMapHeatmap(),
+			config.MapSimplify(),
+		)
+	}
+
+	return "", fmt.Errorf("unknown input kind '%s'", kind)
+}
+
+func documentInput(doc *data.Document) string  { return "document:" + doc.Path }
+func metaInput(doc *data.Document) string      { return "meta:" + doc.Path }
+func neighborsInput(doc *data.Document) string { return "neighbors:" + doc.Path }
+func assetInput(path string) string            { return "asset:" + path }
+
+func metaInputs(docs []*data.Document) []string {
+	inputs := make([]string, len(docs))
+	for i, doc := range docs {
+		inputs[i] = metaInput(doc)
+	}
+
+	return inputs
+}
+
+func hashValue(values ...any) (string, error) {
+	payloadBytes, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(payloadBytes)
+
+	return hex.EncodeToString(sum[:16]), nil
+}
+
+// hashFS computes a hash of all file names and contents of the file system.
+func hashFS(fsys fs.FS) (string, error) {
+	h := sha256.New()
+
+	err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		f, err := fsys.Open(path)
+		if err != nil {
+			return err
+		}
+
+		defer func() { _ = f.Close() }()
+
+		_, _ = io.WriteString(h, path+"\x00")
+		_, err = io.Copy(h, f)
+
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil))[:32], nil
+}
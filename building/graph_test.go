@@ -0,0 +1,271 @@
+package building
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/bgraf/rueckblick/data"
+	"github.com/bgraf/rueckblick/filesystem"
+)
+
+// testJournal is a journal of one entry per day in April 2023 together with its build
+// directory.
+type testJournal struct {
+	t    *testing.T
+	opts Options
+}
+
+func newTestJournal(t *testing.T, titles ...string) *testJournal {
+	j := &testJournal{
+		t:    t,
+		opts: Options{JournalDirectory: t.TempDir(), BuildDirectory: t.TempDir()},
+	}
+
+	for i, title := range titles {
+		j.writeDocument(i+1, title, "")
+	}
+
+	return j
+}
+
+// writeDocument writes the document of the given day with a general tag, if not empty.
+func (j *testJournal) writeDocument(day int, title string, tag string) {
+	j.t.Helper()
+
+	date := time.Date(2023, 4, day, 0, 0, 0, 0, time.UTC).Format("2006-01-02")
+
+	directory := filepath.Join(j.opts.JournalDirectory, "2023", date)
+	if err := os.MkdirAll(directory, 0o700); err != nil {
+		j.t.Fatal(err)
+	}
+
+	source := fmt.Sprintf("---\ntitle: %s\ndate: %s\n", title, date)
+	if tag != "" {
+		source += fmt.Sprintf("tags:\n  general: [%s]\n", tag)
+	}
+	source += "---\nBody\n"
+
+	path := filepath.Join(directory, fmt.Sprintf("doc_%s.md", date))
+	if err := os.WriteFile(path, []byte(source), 0o666); err != nil {
+		j.t.Fatal(err)
+	}
+
+	// Make sure the change is noticed despite a coarse modification time.
+	modTime := time.Now().Add(time.Duration(day) * time.Second)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		j.t.Fatal(err)
+	}
+}
+
+func (j *testJournal) removeDocument(day int) {
+	j.t.Helper()
+
+	date := time.Date(2023, 4, day, 0, 0, 0, 0, time.UTC).Format("2006-01-02")
+	if err := os.RemoveAll(filepath.Join(j.opts.JournalDirectory, "2023", date)); err != nil {
+		j.t.Fatal(err)
+	}
+}
+
+func (j *testJournal) build() {
+	j.t.Helper()
+
+	if err := Build(j.opts); err != nil {
+		j.t.Fatal(err)
+	}
+}
+
+func (j *testJournal) path(file string) string {
+	return filepath.Join(j.opts.BuildDirectory, file)
+}
+
+// markStale overwrites the files in the build directory, such that regenerated ones can
+// be told apart from kept ones.
+func (j *testJournal) markStale(files ...string) {
+	j.t.Helper()
+
+	for _, file := range files {
+		if err := os.WriteFile(j.path(file), []byte("stale"), 0o666); err != nil {
+			j.t.Fatal(err)
+		}
+	}
+}
+
+func (j *testJournal) isStale(file string) bool {
+	j.t.Helper()
+
+	content, err := os.ReadFile(j.path(file))
+	if err != nil {
+		j.t.Fatal(err)
+	}
+
+	return string(content) == "stale"
+}
+
+func (j *testJournal) glob(pattern string) []string {
+	j.t.Helper()
+
+	files, err := filepath.Glob(j.path(pattern))
+	if err != nil {
+		j.t.Fatal(err)
+	}
+
+	for i, file := range files {
+		files[i] = filepath.Base(file)
+	}
+
+	return files
+}
+
+func TestBuildTemplateChangeRebuildsEverything(t *testing.T) {
+	j := newTestJournal(t, "A", "B")
+	j.build()
+
+	files := []string{"2023-04-01-a.html", "2023-04-02-b.html", "index.html", "cal-2023-04.html"}
+	j.markStale(files...)
+
+	// Nothing changed, nothing is regenerated.
+	j.build()
+
+	for _, file := range files {
+		if !j.isStale(file) {
+			t.Errorf("%s regenerated without changes", file)
+		}
+	}
+
+	// Pretend the templates of the previous build differed.
+	cache, err := readBuildCache(j.opts.BuildDirectory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cache.Templates = "changed"
+
+	payload, err := json.Marshal(cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(j.path(cacheFileName), payload, 0o666); err != nil {
+		t.Fatal(err)
+	}
+
+	j.build()
+
+	for _, file := range files {
+		if j.isStale(file) {
+			t.Errorf("%s not regenerated after template change", file)
+		}
+	}
+}
+
+func TestBuildRemovesPagesOfDeletedDocuments(t *testing.T) {
+	j := newTestJournal(t, "A", "B")
+	j.writeDocument(3, "C", "Hiking")
+	j.build()
+
+	tagFiles := j.glob("tag-*.html")
+	if len(tagFiles) == 0 {
+		t.Fatal("no tag file written")
+	}
+
+	j.removeDocument(3)
+	j.build()
+
+	if filesystem.Exists(j.path("2023-04-03-c.html")) {
+		t.Error("page of deleted document kept")
+	}
+
+	for _, file := range tagFiles {
+		if slices.Contains(j.glob("tag-*.html"), file) {
+			t.Errorf("page of deleted tag %s kept", file)
+		}
+	}
+
+	for _, file := range []string{"2023-04-01-a.html", "2023-04-02-b.html"} {
+		if !filesystem.Exists(j.path(file)) {
+			t.Errorf("page of remaining document %s removed", file)
+		}
+	}
+}
+
+func TestBuildDocumentChangeRebuildsNeighbors(t *testing.T) {
+	j := newTestJournal(t, "A", "B", "C", "D")
+	j.build()
+
+	files := []string{"2023-04-01-a.html", "2023-04-03-c.html", "2023-04-04-d.html"}
+	j.markStale(files...)
+
+	// The neighbors link to the document by its title.
+	j.writeDocument(2, "Renamed", "")
+	j.build()
+
+	if !filesystem.Exists(j.path("2023-04-02-renamed.html")) {
+		t.Error("page of changed document not written")
+	}
+
+	if filesystem.Exists(j.path("2023-04-02-b.html")) {
+		t.Error("page of changed document with its previous name kept")
+	}
+
+	for _, file := range []string{"2023-04-01-a.html", "2023-04-03-c.html"} {
+		if j.isStale(file) {
+			t.Errorf("neighbor %s not regenerated", file)
+		}
+	}
+
+	if !j.isStale("2023-04-04-d.html") {
+		t.Error("unrelated 2023-04-04-d.html regenerated")
+	}
+}
+
+func TestRemoveObsoleteKeepsFailedDocuments(t *testing.T) {
+	buildDirectory := t.TempDir()
+
+	for _, file := range []string{"broken.html", "deleted.html"} {
+		if err := os.WriteFile(filepath.Join(buildDirectory, file), nil, 0o666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	state := &buildState{
+		Options: Options{BuildDirectory: buildDirectory},
+		store: &data.Store{
+			Errors: []*data.DocumentError{
+				data.NewDocumentError("/journal/broken.md", data.StageFrontMatter, errors.New("invalid")),
+			},
+		},
+	}
+	state.Initialize()
+
+	previous := map[string]outputRecord{
+		entryOutput("/journal/broken.md"): {
+			Files:  []string{"broken.html"},
+			Inputs: map[string]string{"document:/journal/broken.md": "a"},
+		},
+		entryOutput("/journal/deleted.md"): {
+			Files:  []string{"deleted.html"},
+			Inputs: map[string]string{"document:/journal/deleted.md": "b"},
+		},
+	}
+	state.graph = newDepGraph(state, previous, false)
+
+	collectChangedDocuments(state)
+
+	if err := state.graph.RemoveObsolete(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !filesystem.Exists(filepath.Join(buildDirectory, "broken.html")) {
+		t.Error("page of failed document was removed")
+	}
+
+	if filesystem.Exists(filepath.Join(buildDirectory, "deleted.html")) {
+		t.Error("page of deleted document was kept")
+	}
+}
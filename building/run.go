@@ -2,23 +2,28 @@ package building
 
 import (
 	"bytes"
-	"errors"
 	"fmt"
 	"html/template"
 	"log"
+	"maps"
 	"os"
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"runtime/debug"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/bgraf/rueckblick/config"
 	"github.com/bgraf/rueckblick/data"
 	"github.com/bgraf/rueckblick/filesystem"
+	"github.com/bgraf/rueckblick/metacache"
 	"github.com/bgraf/rueckblick/render"
 	"github.com/bgraf/rueckblick/res"
+	"github.com/bgraf/rueckblick/tiles"
 	"github.com/bgraf/rueckblick/util/dates"
 )
 
@@ -49,6 +54,10 @@ type Options struct {
 	Clean            bool
 	JournalDirectory string
 	BuildDirectory   string
+
+	// KeepGoing continues the build when documents fail, such that all other pages are
+	// written. The failures are still reported by the returned error.
+	KeepGoing bool
 }
 
 func Build(opts Options) error {
@@ -56,12 +65,18 @@ func Build(opts Options) error {
 		return fmt.Errorf("could not ensure build directory: %w", err)
 	}
 
+	if err := filesystem.CreateDirectoryIfNotExists(filepath.Join(opts.BuildDirectory, fragmentDirectory)); err != nil {
+		return fmt.Errorf("could not ensure fragment directory: %w", err)
+	}
+
 	templates, err := render.ReadTemplates(Filenamer{})
 	if err != nil {
 		return err
 	}
 
-	store, err := data.NewDefaultStore(opts.JournalDirectory)
+	metadata := metacache.Open(opts.BuildDirectory)
+
+	store, assets, err := openStore(opts.JournalDirectory, opts.BuildDirectory, metadata)
 	if err != nil {
 		return err
 	}
@@ -71,148 +86,202 @@ func Build(opts Options) error {
 		templates: templates,
 		store:     store,
 		filenamer: Filenamer{},
+		assets:    assets,
+		metadata:  metadata,
 	}
 	state.Initialize()
 
-	changedDocuments, err := collectPrimaryChangeDocuments(state)
-	if err != nil {
-		return err
+	for _, err := range store.Errors {
+		state.diagnostics.Report(err)
+	}
+
+	if !opts.KeepGoing && state.diagnostics.Len() > 0 {
+		return state.diagnostics.Err()
 	}
 
-	// Use the build cache to determine additional documents that need rerendering, because
-	// of new neighboring documents.
-	//
-	// Example: assume the current most recent article is X, without any successor. If
-	// we add a new most recent article Y, then X need to point to Y, i.e., X needs to be
-	// rerendered despite not being modified.
+	searchIndex := makeSearchIndex(state)
+	state.trackFiles = collectTrackFiles(state)
 
 	currentCache, err := readBuildCache(state.BuildDirectory)
 	if err != nil {
 		log.Printf("could not read cache: %v\n", err)
 	}
 
-	nextCache := makeBuildCache(state)
+	var nextCache buildCache
 
-	log.Printf("curr cache has %d entries\n", len(currentCache.Documents))
-	log.Printf("next cache has %d entries\n", len(nextCache.Documents))
+	nextCache.Templates, err = hashTemplates(opts.BuildDirectory)
+	if err != nil {
+		return fmt.Errorf("could not hash templates: %w", err)
+	}
 
-	addToDocumentSet := func(entry cacheDocument) {
-		for _, doc := range state.store.Documents {
-			if doc.Path == entry.Path {
-				changedDocuments.Add(doc)
-				return
-			}
-		}
+	nextCache.Static, err = hashFS(res.Static)
+	if err != nil {
+		return fmt.Errorf("could not hash static files: %w", err)
 	}
 
-	for i, entry := range nextCache.Documents {
-		iOld := -1
-		for j, e := range currentCache.Documents {
-			if e.Path == entry.Path {
-				iOld = j
-				break
-			}
-		}
+	// Templates, the program itself and the tile providers determine the appearance of
+	// every page, thus rebuild everything if they changed.
+	fullRebuild := opts.Clean || nextCache.Templates != currentCache.Templates
+	if !opts.Clean && fullRebuild {
+		log.Println("templates or program changed, rebuilding everything")
+	}
 
-		if iOld < 0 {
-			addToDocumentSet(entry)
-			continue
-		}
+	state.graph = newDepGraph(state, currentCache.Outputs, fullRebuild)
 
-		if (i == 0) != (iOld == 0) {
-			// Acquired or lost predecessor => rebuild!
-			addToDocumentSet(entry)
-			continue
-		}
+	log.Printf("previous build has %d outputs\n", len(currentCache.Outputs))
 
-		if i > 0 && iOld > 0 {
-			// Compare prior documents
-			prior := nextCache.Documents[i-1]
-			priorOld := currentCache.Documents[iOld-1]
-			if prior.OutputPath != priorOld.OutputPath {
-				addToDocumentSet(entry)
-				continue
-			}
-		}
+	if err := processEntryFiles(state, collectChangedDocuments(state)); err != nil {
+		return err
+	}
 
-		if (i+1 == len(nextCache.Documents)) != (iOld+1 == len(currentCache.Documents)) {
-			// Acquired or lost successor => rebuild!
-			addToDocumentSet(entry)
-			continue
-		}
+	if !opts.KeepGoing && state.diagnostics.Len() > 0 {
+		return state.diagnostics.Err()
+	}
 
-		if i+1 < len(nextCache.Documents) && iOld+1 < len(currentCache.Documents) {
-			succ := nextCache.Documents[i+1]
-			succOld := currentCache.Documents[iOld+1]
-			if succ.OutputPath != succOld.OutputPath {
-				addToDocumentSet(entry)
-				continue
-			}
-		}
+	periodByDate := make(map[time.Time]data.Period)
+	for _, period := range store.Periods {
+		dates.ForEachDay(period.From, period.To, func(t time.Time) {
+			periodByDate[dates.ToLocal(t)] = period
+		})
 	}
 
-	if changedDocuments.Len() == 0 {
-		fmt.Println(("nothing to do"))
+	getPeriod := func(t time.Time) *data.Period {
+		if p, ok := periodByDate[t]; ok {
+			return &p
+		}
+
 		return nil
 	}
 
-	if err := processEntryFiles(state, changedDocuments); err != nil {
+	if err := writeIndexFile(state); err != nil {
 		return err
 	}
 
-	if changedDocuments.Len() > 0 {
-		periodByDate := make(map[time.Time]data.Period)
-		for _, period := range store.Periods {
-			dates.ForEachDay(period.From, period.To, func(t time.Time) {
-				periodByDate[dates.ToLocal(t)] = period
-			})
-		}
-
-		getPeriod := func(t time.Time) *data.Period {
-			if p, ok := periodByDate[t]; ok {
-				return &p
-			}
+	if err := writeTagFiles(state); err != nil {
+		return err
+	}
 
-			return nil
-		}
+	if err := writeTagsIndexFile(state); err != nil {
+		return err
+	}
 
-		if err := writeIndexFile(state); err != nil {
-			return err
-		}
+	if err := writeCalendarFiles(state, getPeriod); err != nil {
+		return err
+	}
 
-		if err := writeTagFiles(state); err != nil {
-			return err
-		}
+	if err := writeSearchFile(state); err != nil {
+		return err
+	}
 
-		if err := writeTagsIndexFile(state); err != nil {
-			return err
-		}
+	if err := writeFeeds(state); err != nil {
+		return err
+	}
 
-		if err := writeCalendarFiles(state, getPeriod); err != nil {
-			return err
-		}
+	if err := writeGlobalMap(state); err != nil {
+		return err
+	}
 
-		// TODO: replace constant "res" by some globally configurable value
-		if err := filesystem.InstallEmbedFS(res.Static, filepath.Join(state.BuildDirectory, "res")); err != nil {
+	// TODO: replace constant "res" by some globally configurable value
+	resDirectory := filepath.Join(state.BuildDirectory, "res")
+	if nextCache.Static != currentCache.Static || !filesystem.IsDirectory(resDirectory) {
+		if err := filesystem.InstallEmbedFS(res.Static, resDirectory); err != nil {
 			return fmt.Errorf("installation of state files failed: %w", err)
 		}
 	}
 
-	if err := writeBuildCache(state); err != nil {
+	if err := writeSearchIndex(state, searchIndex); err != nil {
+		return err
+	}
+
+	if err := state.graph.RemoveObsolete(); err != nil {
+		return err
+	}
+
+	if err := writeBuildCache(state, nextCache); err != nil {
 		log.Fatalf("write build cache: %s", err)
 	}
 
+	if err := metadata.Save(); err != nil {
+		return err
+	}
+
+	if err := state.diagnostics.Err(); err != nil {
+		return err
+	}
+
+	if state.graph.Written() == 0 {
+		fmt.Println("nothing to do")
+		return nil
+	}
+
 	log.Println("done")
 
 	return nil
 }
 
+// hashTemplates hashes the templates together with the configuration they depend on and
+// the running executable.
+func hashTemplates(buildDirectory string) (string, error) {
+	hash, err := hashFS(res.Templates)
+	if err != nil {
+		return "", err
+	}
+
+	version, err := executableVersion()
+	if err != nil {
+		return "", err
+	}
+
+	return hashValue(
+		hash,
+		version,
+		config.HasFeedBaseURL(),
+		config.HasHomeCoords(),
+		tiles.Providers(buildDirectory),
+		config.PhotoTolerance(),
+		config.FieldSchemas(),
+		config.TagCategories(),
+	)
+}
+
+// executableVersion identifies the running executable by the checksum of its module or its
+// VCS revision. Only if the build info lacks both, e.g., for builds of modified working
+// trees, the executable is hashed.
+func executableVersion() (string, error) {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if info.Main.Sum != "" {
+			return info.Main.Version + " " + info.Main.Sum, nil
+		}
+
+		settings := make(map[string]string)
+		for _, setting := range info.Settings {
+			settings[setting.Key] = setting.Value
+		}
+
+		if revision := settings["vcs.revision"]; revision != "" && settings["vcs.modified"] == "false" {
+			return revision, nil
+		}
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+
+	return hashFile(executable)
+}
+
 type buildState struct {
 	Options
 	templates   *template.Template
 	store       *data.Store
 	indexbyPath map[string]int
 	filenamer   Filenamer
+	assets      *assetTree
+	graph       *depGraph
+	diagnostics diagnostics
+	metadata    *metacache.Cache
+	trackFiles  map[string][]string // Track files of the maps by document path
 }
 
 func (state *buildState) Initialize() {
@@ -243,49 +312,58 @@ func (state *buildState) WriteFile(path string, content []byte) error {
 	return os.WriteFile(p, content, 0o666)
 }
 
-func collectPrimaryChangeDocuments(state *buildState) (*DocumentSet, error) {
-	s := NewDocumentSet()
+func entryOutput(path string) string {
+	return "entry:" + path
+}
 
-	for _, doc := range state.store.Documents {
-		performUpdate := true
+// entryInputs returns the inputs of an entry page known before rendering it.
+func entryInputs(state *buildState, doc *data.Document) []string {
+	inputs := []string{documentInput(doc), metaInput(doc), neighborsInput(doc)}
 
-		if !state.Clean {
-			documentModTime, err := filesystem.FullSubtreeModifiedDate(doc.DocumentDirectory())
-			if err != nil {
-				return nil, err
-			}
+	i := state.Index(doc)
+	if i > 0 {
+		inputs = append(inputs, metaInput(state.store.Documents[i-1]))
+	}
+	if i+1 < len(state.store.Documents) {
+		inputs = append(inputs, metaInput(state.store.Documents[i+1]))
+	}
 
-			entryFile := filepath.Join(state.BuildDirectory, state.filenamer.EntryFile(doc))
-			resultModTime, err := filesystem.FileModifiedTime(entryFile)
-			if err != nil {
-				if errors.Is(err, os.ErrNotExist) {
-					performUpdate = true
-				} else {
-					return nil, err
-				}
-			} else {
-				performUpdate = resultModTime.Before(documentModTime)
-			}
-		}
+	return inputs
+}
 
-		if !performUpdate {
-			continue
+func collectChangedDocuments(state *buildState) *DocumentSet {
+	s := NewDocumentSet()
+
+	for _, doc := range state.store.Documents {
+		if state.graph.Stale(entryOutput(doc.Path), entryInputs(state, doc)) {
+			s.Add(doc)
 		}
+	}
 
-		s.Add(doc)
+	// Declare the pages of documents which failed to load without recording them, such
+	// that their previous pages are kept until the documents are fixed.
+	for _, err := range state.store.Errors {
+		state.graph.Stale(entryOutput(err.Path), nil)
 	}
 
-	return s, nil
+	return s
 }
 
 type isValidDate = func(t time.Time) bool
 
+const currentCalendarFileName = "current-calendar.html"
+
 func writeCalendarFiles(
 	state *buildState,
 	getPeriod func(t time.Time) *data.Period,
 ) error {
 	store := state.store
 
+	// Without documents there is no calendar, previous calendar files are obsolete.
+	if len(store.Documents) == 0 {
+		return nil
+	}
+
 	end := dates.FirstDayOfMonth(store.Documents[0].Date).AddDate(0, 0, 1)
 	first := dates.FirstDayOfMonth(store.Documents[len(store.Documents)-1].Date)
 
@@ -295,6 +373,10 @@ func writeCalendarFiles(
 		}
 	}(first, end)
 
+	// The latest monthly calendar file is also written as current calendar, so the links
+	// from all entry pages to the current calendar always link to the latest.
+	latest := dates.FirstDayOfMonth(store.Documents[0].Date)
+
 	for first.Before(end) {
 		err := writeCalendarFile(
 			state,
@@ -302,6 +384,7 @@ func writeCalendarFiles(
 			int(first.Month()),
 			isValid,
 			getPeriod,
+			first.Equal(latest),
 		)
 		if err != nil {
 			return err
@@ -310,16 +393,6 @@ func writeCalendarFiles(
 		first = dates.AddMonths(first, 1)
 	}
 
-	// Copy latest monthly calendar file as current calendar,
-	// so the links from all entry pages to the current calendar always
-	// link to the latest.
-	lastDate := dates.FirstDayOfMonth(store.Documents[0].Date)
-	latestCalendarFile := filepath.Join(state.BuildDirectory, state.filenamer.CalendarFile(lastDate.Year(), int(lastDate.Month())))
-	currentCalendarFile := filepath.Join(state.BuildDirectory, "current-calendar.html")
-	if err := filesystem.Copy(latestCalendarFile, currentCalendarFile); err != nil {
-		return err
-	}
-
 	return nil
 }
 
@@ -328,14 +401,16 @@ func writeCalendarFile(
 	year, month int,
 	isValidDate isValidDate,
 	getPeriod func(t time.Time) *data.Period,
+	isLatest bool,
 ) error {
 	type calendarDay struct {
-		Date     time.Time
-		Document *data.Document
-		Period   *data.Period
+		Date      time.Time
+		Documents []*data.Document // Chronologically ordered
+		Period    *data.Period
 	}
 
 	var calendarDays []calendarDay
+	var documents []*data.Document
 
 	startDate := dates.FromYM(year, month)
 	endDate := dates.LastDayOfMonth(startDate)
@@ -343,19 +418,22 @@ func writeCalendarFile(
 	endDate = dates.NextSunday(endDate)
 
 	dates.ForEachDay(startDate, endDate, func(curr time.Time) {
-		var doc *data.Document
-
-		if docs := state.store.DocumentsOnDate(curr); len(docs) > 0 {
-			doc = docs[0]
-		}
+		docs := state.store.DocumentsOnDate(curr)
+		documents = append(documents, docs...)
 
 		calendarDays = append(calendarDays, calendarDay{
-			Document: doc,
-			Date:     curr,
-			Period:   getPeriod(curr),
+			Documents: docs,
+			Date:      curr,
+			Period:    getPeriod(curr),
 		})
 	})
 
+	output := fmt.Sprintf("calendar:%04d-%02d", year, month)
+	inputs := append([]string{"calendar-range", "periods"}, metaInputs(documents)...)
+	if !state.graph.Stale(output, inputs) {
+		return nil
+	}
+
 	currMonth := dates.FromYM(year, month)
 
 	prevMonth := dates.AddMonths(currMonth, -1)
@@ -380,14 +458,21 @@ func writeCalendarFile(
 		return fmt.Errorf("could not execute template: %w", err)
 	}
 
-	fileName := state.filenamer.CalendarFile(year, month)
+	files := []string{state.filenamer.CalendarFile(year, month)}
+	if isLatest {
+		files = append(files, currentCalendarFileName)
+	}
+
+	for _, fileName := range files {
+		err = state.WriteFile(fileName, buf.Bytes())
+		if err != nil {
+			return fmt.Errorf("could not write calendar file: %w", err)
+		}
 
-	err = state.WriteFile(fileName, buf.Bytes())
-	if err != nil {
-		return fmt.Errorf("could not write calendar file: %w", err)
+		log.Printf("written calendar file '%s'", fileName)
 	}
 
-	log.Printf("written calendar file '%s'", fileName)
+	state.graph.Record(output, files, inputs)
 
 	return nil
 }
@@ -406,6 +491,11 @@ func writeIndexFile(
 		m[year] = append(m[year], group)
 	}
 
+	documentsByYear := make(map[int][]*data.Document)
+	for _, doc := range state.store.Documents {
+		documentsByYear[doc.Date.Year()] = append(documentsByYear[doc.Date.Year()], doc)
+	}
+
 	type yearsMenu struct {
 		Year       int
 		LinkTarget string
@@ -429,7 +519,18 @@ func writeIndexFile(
 		return yearMenus[i].Year > yearMenus[j].Year
 	})
 
+	// An empty journal still gets an empty index.
+	if len(m) == 0 {
+		m[latestYear] = nil
+	}
+
 	for year, groups := range m {
+		output := fmt.Sprintf("index:%d", year)
+		inputs := append([]string{"years"}, metaInputs(documentsByYear[year])...)
+		if !state.graph.Stale(output, inputs) {
+			continue
+		}
+
 		var buf bytes.Buffer
 		err := state.templates.ExecuteTemplate(&buf, "index.html", map[string]interface{}{
 			"YearMenus": yearMenus,
@@ -446,30 +547,36 @@ func writeIndexFile(
 			return fmt.Errorf("could not write index file: %w", err)
 		}
 
+		state.graph.Record(output, []string{filename}, inputs)
 	}
 
 	return nil
 }
 
 func writeTagsIndexFile(state *buildState) error {
-	// Prepare tags
-	tagsByCategory := state.store.TagsByCategory()
-	tags := []struct {
+	inputs := []string{"tags", "periods"}
+	if !state.graph.Stale("tags", inputs) {
+		return nil
+	}
+
+	// Prepare tags of the configured categories followed by other ones. Periods are listed
+	// separately.
+	type tagGroup struct {
 		Category string
 		Tags     []data.Tag
-	}{
-		{
-			Category: "Orte",
-			Tags:     tagsByCategory["location"],
-		},
-		{
-			Category: "Personen",
-			Tags:     tagsByCategory["people"],
-		},
-		{
-			Category: "Andere",
-			Tags:     tagsByCategory["general"],
-		},
+	}
+
+	tagsByCategory := state.store.TagsByCategory()
+	delete(tagsByCategory, "period")
+
+	var tags []tagGroup
+	for _, category := range config.TagCategories() {
+		tags = append(tags, tagGroup{category.Label, tagsByCategory[category.Key]})
+		delete(tagsByCategory, category.Key)
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(tagsByCategory)) {
+		tags = append(tags, tagGroup{key, tagsByCategory[key]})
 	}
 
 	for k := range tags {
@@ -480,7 +587,7 @@ func writeTagsIndexFile(state *buildState) error {
 	}
 
 	// Prepare periods
-	periods := state.store.Periods
+	periods := slices.Clone(state.store.Periods)
 	sort.Slice(periods, func(i, j int) bool {
 		return periods[i].From.After(periods[j].From)
 	})
@@ -499,6 +606,8 @@ func writeTagsIndexFile(state *buildState) error {
 		return fmt.Errorf("could not write tag file: %w", err)
 	}
 
+	state.graph.Record("tags", []string{"tags.html"}, inputs)
+
 	return nil
 }
 
@@ -507,12 +616,20 @@ func writeTagFiles(state *buildState) error {
 
 	for _, tag := range store.Tags() {
 		documents := store.DocumentsByTagName(tag.Raw)
+
+		output := "tag:" + tag.Raw
+		inputs := metaInputs(documents)
+		if !state.graph.Stale(output, inputs) {
+			continue
+		}
+
 		groups := render.MakeDocumentGroups(documents)
 
 		var buf bytes.Buffer
 		err := state.templates.ExecuteTemplate(&buf, "index.html", map[string]interface{}{
-			"Groups": groups,
-			"Tag":    tag.Raw,
+			"Groups":  groups,
+			"Tag":     tag.Raw,
+			"TagFeed": state.filenamer.TagFeedFile(tag),
 		})
 		if err != nil {
 			return fmt.Errorf("could not execute template: %w", err)
@@ -525,6 +642,8 @@ func writeTagFiles(state *buildState) error {
 			return fmt.Errorf("could not write tag file: %w", err)
 		}
 
+		state.graph.Record(output, []string{fileName}, inputs)
+
 		log.Printf("written tag file '%s'", fileName)
 	}
 
@@ -553,7 +672,8 @@ func processEntryFiles(state *buildState, ds *DocumentSet) error {
 				}
 
 				if err := writeEntryFile(state, doc, docPred, docSucc); err != nil {
-					// TODO: report error
+					log.Printf("could not render entry: %s", err)
+					state.diagnostics.Report(err)
 				}
 			}
 		}(docs)
@@ -576,13 +696,14 @@ func writeEntryFile(
 	docPred *data.Document,
 	docSucc *data.Document,
 ) error {
-	render.Render(doc, *state.store.Options)
+	if err := render.Render(doc, *state.store.Options); err != nil {
+		return err
+	}
 
 	// Extract body fragment
 	fragment, err := state.store.GetHtmlFragment(doc)
 	if err != nil {
-		// TODO: log
-		return fmt.Errorf("failed to build document: %w", err)
+		return data.NewDocumentError(doc.Path, data.StageTemplate, err)
 	}
 
 	var buf bytes.Buffer
@@ -594,16 +715,29 @@ func writeEntryFile(
 		"Fragment":     template.HTML(fragment),
 	})
 	if err != nil {
-		return fmt.Errorf("could not execute template: %w", err)
+		return data.NewDocumentError(doc.Path, data.StageTemplate, err)
 	}
 
 	fileName := state.filenamer.EntryFile(doc)
 
 	err = state.WriteFile(fileName, buf.Bytes())
 	if err != nil {
-		log.Printf("could not write entry file: %s", err)
+		return data.NewDocumentError(doc.Path, data.StageOutput, fmt.Errorf("could not write entry file: %w", err))
 	}
 
+	// Keep the fragment for feeds, which also cover documents that are not rerendered.
+	err = state.WriteFile(fragmentPath(state, doc), []byte(fragment))
+	if err != nil {
+		return data.NewDocumentError(doc.Path, data.StageOutput, fmt.Errorf("could not write fragment file: %w", err))
+	}
+
+	inputs := entryInputs(state, doc)
+	for _, src := range state.assets.Sources(doc) {
+		inputs = append(inputs, assetInput(src))
+	}
+
+	state.graph.Record(entryOutput(doc.Path), []string{fileName, fragmentPath(state, doc)}, inputs)
+
 	log.Printf("rendered entry '%s'", fileName)
 
 	return nil
@@ -0,0 +1,55 @@
+package building
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bgraf/rueckblick/filesystem"
+)
+
+func TestBuildEmptyJournal(t *testing.T) {
+	journalDirectory := t.TempDir()
+	buildDirectory := t.TempDir()
+	opts := Options{JournalDirectory: journalDirectory, BuildDirectory: buildDirectory}
+
+	entryDirectory := filepath.Join(journalDirectory, "2023", "2023-04-02-walk")
+	if err := os.MkdirAll(entryDirectory, 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	document := "---\ntitle: Walk\ndate: 2023-04-02\n---\nBody\n"
+	if err := os.WriteFile(filepath.Join(entryDirectory, "doc_2023-04-02.md"), []byte(document), 0o666); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Build(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	calendarFiles := []string{"cal-2023-04.html", currentCalendarFileName}
+	for _, file := range calendarFiles {
+		if !filesystem.Exists(filepath.Join(buildDirectory, file)) {
+			t.Fatalf("%s not written", file)
+		}
+	}
+
+	// Deleting the last entry leaves an empty journal.
+	if err := os.RemoveAll(filepath.Join(journalDirectory, "2023")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Build(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	if !filesystem.Exists(filepath.Join(buildDirectory, "index.html")) {
+		t.Error("index.html not written")
+	}
+
+	for _, file := range calendarFiles {
+		if filesystem.Exists(filepath.Join(buildDirectory, file)) {
+			t.Errorf("obsolete %s kept", file)
+		}
+	}
+}
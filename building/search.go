@@ -0,0 +1,126 @@
+package building
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/bgraf/rueckblick/render"
+	"golang.org/x/net/html"
+)
+
+// Name of the script file holding the search index. A script is used instead of a JSON
+// file, because browsers refuse to fetch files via `file://` URIs.
+const searchIndexFileName = "search-index.js"
+
+type searchRecord struct {
+	Title    string   `json:"t"`
+	Abstract string   `json:"a,omitempty"`
+	Tags     []string `json:"g,omitempty"`
+	Date     jsonDate `json:"d"`
+	URL      string   `json:"u"`
+	Text     string   `json:"x"`
+}
+
+// makeSearchIndex collects the searchable data of all documents. It must be called before
+// any document is rendered, so the text is not polluted by the contents of galleries or maps.
+func makeSearchIndex(state *buildState) []searchRecord {
+	var records []searchRecord
+
+	for _, doc := range state.store.Documents {
+		var tags []string
+		for _, tag := range doc.Tags {
+			tags = append(tags, tag.String())
+		}
+
+		records = append(records, searchRecord{
+			Title:    doc.Title,
+			Abstract: doc.Abstract,
+			Tags:     tags,
+			Date:     jsonDate(doc.Date),
+			URL:      render.EntryURL(state.filenamer, doc),
+			Text:     plainText(doc.HTML.Find("body")),
+		})
+	}
+
+	return records
+}
+
+// plainText extracts the text of the selection with collapsed white space, ignoring
+// scripts and styles.
+func plainText(s *goquery.Selection) string {
+	var b strings.Builder
+
+	var visit func(n *html.Node)
+	visit = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			b.WriteString(n.Data)
+			b.WriteByte(' ')
+		case html.ElementNode:
+			if n.Data == "script" || n.Data == "style" {
+				return
+			}
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			visit(c)
+		}
+	}
+
+	for _, n := range s.Nodes {
+		visit(n)
+	}
+
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// writeSearchIndex writes the search index script, unless the existing file is up to date.
+func writeSearchIndex(state *buildState, records []searchRecord) error {
+	payloadBytes, err := json.Marshal(records)
+	if err != nil {
+		return fmt.Errorf("could not serialize search index: %w", err)
+	}
+
+	var buf bytes.Buffer
+	_, _ = buf.WriteString("window.searchIndex = ")
+	_, _ = buf.Write(payloadBytes)
+	_, _ = buf.WriteString(";\n")
+
+	current, err := os.ReadFile(filepath.Join(state.BuildDirectory, searchIndexFileName))
+	if err == nil && bytes.Equal(current, buf.Bytes()) {
+		return nil
+	}
+
+	if err := state.WriteFile(searchIndexFileName, buf.Bytes()); err != nil {
+		return fmt.Errorf("could not write search index: %w", err)
+	}
+
+	return nil
+}
+
+func writeSearchFile(state *buildState) error {
+	if !state.graph.Stale("search", nil) {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	err := state.templates.ExecuteTemplate(&buf, "search.html", map[string]any{
+		"IndexURL": "./" + searchIndexFileName,
+	})
+	if err != nil {
+		return fmt.Errorf("could not execute template: %w", err)
+	}
+
+	if err := state.WriteFile("search.html", buf.Bytes()); err != nil {
+		return fmt.Errorf("could not write search file: %w", err)
+	}
+
+	state.graph.Record("search", []string{"search.html"}, nil)
+
+	return nil
+}
@@ -17,6 +17,9 @@ var buildCmd = &cobra.Command{
 	Short: "",
 	Long:  "",
 	RunE:  runBuildCmd,
+
+	// Failing documents are reported by the error, the usage does not help.
+	SilenceUsage: true,
 }
 
 func init() {
@@ -29,6 +32,7 @@ func init() {
 	}
 
 	buildCmd.Flags().BoolP("clean", "C", false, "Clean build everything")
+	buildCmd.Flags().BoolP("keep-going", "k", false, "Write all pages that succeed even if some documents fail")
 }
 
 func runBuildCmd(cmd *cobra.Command, args []string) error {
@@ -59,9 +63,18 @@ func initBuildOptions(cmd *cobra.Command) (building.Options, error) {
 	}
 
 	var err error
-	b.Clean, err = cmd.Flags().GetBool("clean")
-	if err != nil {
-		return b, err
+	if cmd.Flags().Lookup("clean") != nil {
+		b.Clean, err = cmd.Flags().GetBool("clean")
+		if err != nil {
+			return b, err
+		}
+	}
+
+	if cmd.Flags().Lookup("keep-going") != nil {
+		b.KeepGoing, err = cmd.Flags().GetBool("keep-going")
+		if err != nil {
+			return b, err
+		}
 	}
 
 	b.JournalDirectory = filesystem.Abs(config.JournalDirectory())
@@ -5,19 +5,17 @@ import (
 	"io"
 	"log"
 	"os"
-	"path"
 	"path/filepath"
 	"regexp"
 	"slices"
 	"sort"
 	"strings"
 	"time"
-	"unicode"
 
+	"github.com/bgraf/rueckblick/authoring"
 	"github.com/bgraf/rueckblick/cmd/tools"
 	"github.com/bgraf/rueckblick/data"
 	"github.com/bgraf/rueckblick/filesystem"
-	"github.com/bgraf/rueckblick/render"
 	"github.com/bgraf/rueckblick/util/dates"
 
 	"github.com/AlecAivazis/survey/v2"
@@ -25,19 +23,49 @@ import (
 	"github.com/bgraf/rueckblick/config"
 	"github.com/lithammer/fuzzysearch/fuzzy"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
-	"gopkg.in/yaml.v2"
 )
 
 // entryCmd represents the entry command
 var entryCmd = &cobra.Command{
 	Use:   "entry [PHOTO-AND-TRACK-DIRECTORY]",
 	Short: "Interactive process to generate a new entry",
-	RunE:  runGenEntry,
+	Long: `Asks for the date, title, tags and further details of a new entry and creates
+its directory and markdown file. Photos, tracks and extra files of the given
+directory are added to the entry.
+
+For scripts, the entry can be described by a YAML or JSON spec file or by flags,
+in which case nothing is asked and the path of the markdown file is printed. A
+spec file looks like:
+
+  date: 2023-04-02
+  title: Walk in the park
+  tags:
+    location: [Osnabrück]
+  abstract: A short walk.
+  author: me
+  photos: /path/to/photos
+  append_tracks: true
+  append_gallery: true
+  preview: IMG_0042.jpg
+
+Flags take precedence over the spec file. Without a title, the other flags are
+the defaults of the questions.`,
+	RunE:         runGenEntry,
+	SilenceUsage: true,
 }
 
 func init() {
 	genCmd.AddCommand(entryCmd)
+
+	entryCmd.Flags().String("spec", "", "YAML or JSON file describing the entry, - for standard input")
+	entryCmd.Flags().String("date", "", "Date of the entry (YYYY-MM-DD or today)")
+	entryCmd.Flags().String("title", "", "Title of the entry")
+	entryCmd.Flags().StringArrayP("tag", "t", nil, "Tag of the form CATEGORY=NAME, or NAME for a general tag")
+	entryCmd.Flags().String("abstract", "", "Abstract of the entry")
+	entryCmd.Flags().String("author", "", "Author of the entry")
+	entryCmd.Flags().Bool("append-tracks", true, "Append the copied tracks to the document")
+	entryCmd.Flags().Bool("append-gallery", true, "Append the gallery to the document")
+	entryCmd.Flags().String("preview", "", "Photo to generate the preview image from, relative to the photo directory")
 }
 
 var datePattern = regexp.MustCompile(`\d\d\d\d-\d\d-\d\d`)
@@ -51,17 +79,115 @@ func runGenEntry(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("too many arguments")
 	}
 
-	inputDirectory := ""
+	journalDirectory := filesystem.Abs(config.JournalDirectory())
+
+	spec, scripted, err := entrySpecFromFlags(cmd, args)
+	if err != nil {
+		return err
+	}
+
+	if spec.Photos != "" {
+		if s, err := os.Stat(spec.Photos); err != nil || !s.IsDir() {
+			return fmt.Errorf("path '%s' is not a directory", spec.Photos)
+		}
+	}
+
+	if scripted {
+		entry, err := authoring.CreateEntry(journalDirectory, spec)
+		if entry.File != "" {
+			fmt.Println(entry.File)
+		}
+
+		return err
+	}
+
+	return runEntryWizard(journalDirectory, spec)
+}
+
+// entrySpecFromFlags reads the spec file and flags of the command. The entry is scripted
+// if a spec file or title is given.
+func entrySpecFromFlags(cmd *cobra.Command, args []string) (authoring.EntrySpec, bool, error) {
+	spec := authoring.DefaultEntrySpec()
+	flags := cmd.Flags()
+
+	specFile, _ := flags.GetString("spec")
+	if specFile != "" {
+		var err error
+		spec, err = readEntrySpec(specFile)
+		if err != nil {
+			return spec, false, err
+		}
+	}
 
 	if len(args) == 1 {
-		inputDirectory = strings.TrimSpace(args[0])
-		if s, err := os.Stat(inputDirectory); err != nil || !s.IsDir() {
-			return fmt.Errorf("path '%s' is not a directory", inputDirectory)
+		spec.Photos = strings.TrimSpace(args[0])
+	}
+
+	for _, field := range []struct {
+		flag  string
+		value *string
+	}{
+		{"date", &spec.Date},
+		{"title", &spec.Title},
+		{"abstract", &spec.Abstract},
+		{"author", &spec.Author},
+		{"preview", &spec.Preview},
+	} {
+		if flags.Changed(field.flag) {
+			*field.value, _ = flags.GetString(field.flag)
 		}
 	}
 
-	journalDirectory := filesystem.Abs(config.JournalDirectory())
+	if flags.Changed("append-tracks") {
+		spec.AppendTracks, _ = flags.GetBool("append-tracks")
+	}
+
+	if flags.Changed("append-gallery") {
+		spec.AppendGallery, _ = flags.GetBool("append-gallery")
+	}
+
+	tags, _ := flags.GetStringArray("tag")
+	for _, tag := range tags {
+		category, name, found := strings.Cut(tag, "=")
+		if !found {
+			category, name = "general", tag
+		}
+
+		category, name = strings.TrimSpace(category), strings.TrimSpace(name)
+		if category == "" || name == "" {
+			return spec, false, fmt.Errorf("invalid tag '%s'", tag)
+		}
+
+		if spec.Tags == nil {
+			spec.Tags = make(map[string][]string)
+		}
+
+		spec.Tags[category] = append(spec.Tags[category], name)
+	}
 
+	return spec, specFile != "" || flags.Changed("title"), nil
+}
+
+// readEntrySpec reads a YAML or JSON spec file, or standard input if the path is "-".
+func readEntrySpec(path string) (authoring.EntrySpec, error) {
+	r := io.Reader(os.Stdin)
+
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return authoring.DefaultEntrySpec(), fmt.Errorf("could not read spec: %w", err)
+		}
+
+		defer func() { _ = f.Close() }()
+
+		r = f
+	}
+
+	return authoring.ReadEntrySpec(r)
+}
+
+// runEntryWizard asks for the details of a new entry and creates it.
+func runEntryWizard(journalDirectory string, spec authoring.EntrySpec) error {
 	// Read store to access the tags for auto-completion
 	// TODO: only read the tags of all documents, do not process the whole store...
 	store, err := data.NewDefaultStore(journalDirectory)
@@ -69,23 +195,34 @@ func runGenEntry(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	date := promptDate(inputDirectory)
+	for _, err := range store.Errors {
+		log.Printf("skipping document: %s", err)
+	}
+
+	if spec.Date != "" {
+		if _, err := authoring.ParseDate(spec.Date); err != nil {
+			return err
+		}
+	}
+
+	inputDirectory := spec.Photos
+
+	date := promptDate(inputDirectory, spec.Date)
+	spec.Date = dates.DateString(date)
 
 	// Read title
-	title := ""
 	{
 		prompt := survey.Input{
 			Message: "Title",
 		}
 		err := survey.AskOne(
 			&prompt,
-			&title,
+			&spec.Title,
 			survey.WithValidator(survey.Required),
 			survey.WithValidator(
 				func(ans interface{}) error {
-					normTitle := normalizeTitle(ans.(string))
-					if len(normTitle) == 0 {
-						return fmt.Errorf("empty normalized title, try letters and digits")
+					if len(authoring.NormalizeTitle(ans.(string))) == 0 {
+						return authoring.ErrEmptyNormalizedTitle
 					}
 					return nil
 				},
@@ -94,6 +231,12 @@ func runGenEntry(cmd *cobra.Command, args []string) error {
 		exitOnInterrupt(err)
 	}
 
+	// Suggest location tags from the positions of the input's tracks and photos
+	var suggestedLocations []string
+	if len(inputDirectory) > 0 {
+		suggestedLocations = promptSuggestedLocations(store, inputDirectory)
+	}
+
 	// Group tags by category
 	knownTags := make(map[string][]string)
 	for _, tag := range store.Tags() {
@@ -114,24 +257,28 @@ func runGenEntry(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	tagPrompts := []struct {
-		message  string
-		category string
-	}{
-		{"Location", "location"},
-		{"People", "people"},
-		{"Tag", "general"},
+	// Tags given by flags are kept and extended.
+	if spec.Tags == nil {
+		spec.Tags = make(map[string][]string)
+	}
+
+	for _, location := range suggestedLocations {
+		if !slices.Contains(spec.Tags["location"], location) {
+			spec.Tags["location"] = append(spec.Tags["location"], location)
+		}
 	}
 
-	tagMap := make(map[string][]string)
+	for _, category := range config.TagCategories() {
+		if category.Prompt == "" {
+			continue
+		}
 
-	for _, tagPrompt := range tagPrompts {
-		var results []string
+		results := spec.Tags[category.Key]
 
 		for {
 			prompt := survey.Input{
-				Message: tagPrompt.message,
-				Suggest: filterSuggestions(knownTags[tagPrompt.category]),
+				Message: category.Prompt,
+				Suggest: filterSuggestions(knownTags[category.Key]),
 			}
 			result := ""
 			err := survey.AskOne(&prompt, &result)
@@ -147,65 +294,37 @@ func runGenEntry(cmd *cobra.Command, args []string) error {
 		}
 
 		if len(results) > 0 {
-			tagMap[tagPrompt.category] = results
+			spec.Tags[category.Key] = results
 		}
 	}
 
-	var abstract string
 	{
 		prompt := survey.Input{
 			Message: "Abstract (optional)",
+			Default: spec.Abstract,
 		}
 
-		err := survey.AskOne(&prompt, &abstract)
+		err := survey.AskOne(&prompt, &spec.Abstract)
 		exitOnInterrupt(err)
-
-		abstract = strings.TrimSpace(abstract)
 	}
 
-	author := os.Getenv("USER")
 	{
 		prompt := survey.Input{
 			Message: "Author",
-			Default: author,
+			Default: spec.Author,
 		}
 		err := survey.AskOne(
 			&prompt,
-			&author,
+			&spec.Author,
 		)
 		exitOnInterrupt(err)
 	}
 
-	dateStr := dates.DateString(date)
-
-	normTitle := normalizeTitle(title)
-	entryDirName := fmt.Sprintf("%s-%s", dateStr, normTitle)
-	entryDir := filepath.Join(journalDirectory, fmt.Sprint(date.Year()), entryDirName)
-	entryFileName := fmt.Sprintf("doc_%s.md", dateStr)
-	entryFile := filepath.Join(entryDir, entryFileName)
-
-	log.Printf("entry directory: %s", entryDir)
-	log.Printf("entry file: %s", entryFile)
-
-	// Setup front matter
-
-	if len(tagMap) == 0 {
-		tagMap = nil
-	}
-
-	frontMatter := data.FrontMatter{
-		Title:    title,
-		Date:     data.YamlDate(date),
-		Author:   author,
-		Tags:     tagMap,
-		Abstract: abstract,
-	}
-
 	// Review front matter
 	{
-		err := writeFrontMatter(os.Stdout, frontMatter)
+		err := authoring.WriteFrontMatter(os.Stdout, spec.FrontMatter(date))
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
 
 		isConfirmed := true
@@ -219,48 +338,52 @@ func runGenEntry(cmd *cobra.Command, args []string) error {
 		exitOnInterrupt(err)
 
 		if !isConfirmed {
-			os.Exit(0)
+			return nil
 		}
 	}
 
-	// Create entry directory
-	err = os.MkdirAll(entryDir, 0700)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	// Create markdown file
-	f, err := os.Create(entryFile)
-	if err != nil {
-		log.Fatal(err)
+	if len(inputDirectory) > 0 {
+		for _, question := range []struct {
+			message string
+			answer  *bool
+		}{
+			{"Append tracks to document", &spec.AppendTracks},
+			{"Append gallery to document", &spec.AppendGallery},
+		} {
+			err := survey.AskOne(
+				&survey.Confirm{Message: question.message, Default: *question.answer},
+				question.answer,
+			)
+			exitOnInterrupt(err)
+		}
 	}
-	defer func() {
-		_ = f.Close()
-	}()
 
-	err = writeFrontMatter(f, frontMatter)
-	if err != nil {
-		log.Fatal(err)
+	entry, err := authoring.CreateEntry(journalDirectory, spec)
+	if entry.File == "" {
+		return err
+	} else if err != nil {
+		log.Printf("Warning: %s\n", err)
 	}
 
-	// Finish
-	log.Print("created entry")
+	entryDir := entry.Directory
 
-	// Generate a gallery if requested by user
-	if len(inputDirectory) > 0 {
-		if err := copyGpxTracks(inputDirectory, entryDir); err != nil {
-			log.Printf("Warning: copying GPX tracks failed: %s\n", err)
+	// Generate a preview if requested by user
+	galleryDirectory := filepath.Join(entryDir, config.DefaultPhotosDirectory())
+	if len(inputDirectory) > 0 && spec.Preview == "" && filesystem.IsDirectory(galleryDirectory) {
+		prompt := &survey.Confirm{
+			Message: "Select a preview image",
+			Default: true,
 		}
 
-		if err := copyExtraFiles(inputDirectory, entryDir); err != nil {
-			log.Printf("Warning: could not copy files: %s\n", err)
-		}
+		isConfirmed := true
+		err := survey.AskOne(prompt, &isConfirmed)
+		exitOnInterrupt(err)
 
-		log.Printf("generating gallery from photos-dir: %s\n", inputDirectory)
-		if err := generateGallery(inputDirectory, entryDir); err != nil {
-			log.Printf("Warning: could not generated gallery: %s\n", err)
+		if isConfirmed {
+			if err := generatePreview(entryDir, galleryDirectory); err != nil {
+				log.Printf("Warning: could not generate preview: %s\n", err)
+			}
 		}
-
 	}
 
 	fmt.Printf("== Change to entry directory ==\n\ncd %s\n\n", entryDir)
@@ -281,7 +404,7 @@ func runGenEntry(cmd *cobra.Command, args []string) error {
 	}
 
 	if runEditor {
-		if err := tools.RunEditor(entryFile, entryDir); err != nil {
+		if err := tools.RunEditor(entry.File, entryDir); err != nil {
 			log.Printf("Error: could not run editor: %s\n", err)
 			return err
 		}
@@ -290,195 +413,73 @@ func runGenEntry(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func copyExtraFiles(inputDirectory string, entryDirectory string) error {
-	configKey := "generate.entry.copy_files_extensions"
-	if !viper.IsSet(configKey) {
-		return nil
-	}
-
-	extensions := viper.GetStringSlice(configKey)
-	if len(extensions) == 0 {
-		return fmt.Errorf("configuration key '%s' has zero entries", configKey)
-	}
-
-	filePaths, err := filesystem.GatherFiles([]string{inputDirectory}, extensions)
+// promptSuggestedLocations lets the user select location tags among the places near the
+// tracks and photos of the input directory.
+func promptSuggestedLocations(store *data.Store, inputDirectory string) []string {
+	ix, err := loadPlaceIndex()
 	if err != nil {
-		return fmt.Errorf("scanning files: %w", err)
-	} else if len(filePaths) == 0 {
+		log.Printf("Warning: could not suggest locations: %s\n", err)
 		return nil
-	}
-
-	/* Video file extensions */
-	videoConfigKey := "generate.entry.video_extensions"
-	videoExtensions := viper.GetStringSlice(videoConfigKey)
-
-	for _, inPath := range filePaths {
-		baseName := path.Base(inPath)
-		outPath := path.Join(entryDirectory, baseName)
-
-		log.Printf("copying extra file '%s'\n", baseName)
-
-		err := filesystem.Copy(inPath, outPath)
-		if err != nil {
-			return err
-		}
-
-		/* Add videos to document */
-		if slices.Contains(videoExtensions, path.Ext(baseName)) {
-			err := filesystem.FindAndAppendToMarkdown(entryDirectory, func(f io.Writer, path string) error {
-				_, _ = fmt.Fprintf(f, "\n<%s src=\"%s\"></%s>\n", render.VideoTagName, baseName, render.VideoTagName)
-				return nil
-			})
-			if err != nil {
-				return err
-			}
-			log.Printf("Added video %s to markdown\n", baseName)
-		}
-	}
-
-	return nil
-}
-
-func copyGpxTracks(inputDirectory string, entryDirectory string) error {
-	// Gather all GPX or NMEA tracks. (NMEA tracks may have .txt extensions)
-	filePaths, err := filesystem.GatherFiles([]string{inputDirectory}, slices.Concat(config.NMEAExtensions(), config.GPXExtensions()))
-	if err != nil {
-		return fmt.Errorf("scanning files: %w", err)
-	} else if len(filePaths) == 0 {
+	} else if ix == nil {
 		return nil
 	}
 
-	// Copy all track files to the journal directory.
-	for _, inPath := range filePaths {
-		outPath := path.Join(entryDirectory, path.Base(inPath))
-
-		err := filesystem.Copy(inPath, outPath)
-		if err != nil {
-			return err
-		}
-	}
-
-	// ask whether to append it to the document
-	appendTracksToDocument := true
-
-	prompt := &survey.Confirm{
-		Message: "Append GPX track to document",
-		Default: appendTracksToDocument,
-	}
-
-	err = survey.AskOne(prompt, &appendTracksToDocument, nil)
+	points, err := collectPositions(inputDirectory)
 	if err != nil {
-		return err
-	}
-
-	if appendTracksToDocument {
-		// Add all tracks to the data.
-		for _, inPath := range filePaths {
-			name := path.Base(inPath)
-			err := filesystem.FindAndAppendToMarkdown(entryDirectory, func(f io.Writer, path string) error {
-				_, _ = fmt.Fprintf(f, "\n<%s track=\"%s\"></%s>\n", render.GPXTagName, name, render.GPXTagName)
-				return nil
-			})
-
-			if err != nil {
-				return err
-			}
-		}
+		log.Printf("Warning: could not suggest locations: %s\n", err)
+		return nil
 	}
 
-	return nil
-}
-
-func generateGallery(photosDirectory string, documentDirectory string) error {
-	opts := defaultGenGalleryOptions()
-	opts.Args = []string{photosDirectory}
-	opts.DocumentDirectory = documentDirectory
-	opts.TargetGalleryDirectory = filepath.Join(opts.DocumentDirectory, opts.TargetGalleryDirectory)
-
-	if err := genGallery(opts); err != nil {
-		return err
+	suggestions := suggestLocations(ix, points, tagsOfCategory(store, "location"))
+	if len(suggestions) == 0 {
+		return nil
 	}
 
-	// Generate preview too?
-	prompt := &survey.Confirm{
-		Message: "Select a preview image",
-		Default: true,
-	}
+	var selected []string
 
-	isConfirmed := true
-	err := survey.AskOne(prompt, &isConfirmed)
+	err = survey.AskOne(
+		&survey.MultiSelect{
+			Message: "Suggested locations",
+			Options: suggestions,
+			Default: suggestions,
+		},
+		&selected,
+	)
 	exitOnInterrupt(err)
 
-	if isConfirmed {
-		err = generatePreview(documentDirectory, opts.TargetGalleryDirectory)
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return selected
 }
 
+// generatePreview lets the user select a photo of the gallery and creates the preview of
+// the entry from it.
 func generatePreview(documentDirectory string, galleryDirectory string) error {
 	sourceImage, err := tools.FehSelectImage(galleryDirectory)
 	if err != nil {
 		return err
 	}
 
-	opts := defaultGenPreviewOptions()
+	opts := authoring.DefaultPreviewOptions()
 	opts.DocumentDirectory = documentDirectory
-	opts.SourceImagePath = sourceImage
+	opts.Source = sourceImage
 
 	return genPreview(opts)
 }
 
-func writeFrontMatter(f io.Writer, fm data.FrontMatter) error {
-	_, _ = fmt.Fprintln(f, "---")
-
-	enc := yaml.NewEncoder(f)
-	err := enc.Encode(fm)
-	if err != nil {
-		return err
-	}
-
-	_, _ = fmt.Fprintln(f, "---")
-
-	return nil
-}
-
 func exitOnInterrupt(err error) {
 	if err == terminal.InterruptErr {
 		os.Exit(1)
 	}
 }
 
-func normalizeTitle(title string) string {
-	var b strings.Builder
-	lastDash := true
-	for _, r := range title {
-		if unicode.IsSpace(r) {
-			if !lastDash {
-				b.WriteString("-")
-				lastDash = true
-			}
-		} else if unicode.IsLetter(r) || unicode.IsDigit(r) {
-			b.WriteRune(unicode.ToLower(r))
-			lastDash = false
-		}
-	}
-
-	return b.String()
-}
-
-func isTodayAnswer(s string) bool {
-	return s == "today" || s == "heute"
-}
-
-func promptDate(inputDirectory string) time.Time {
+// promptDate asks for the date of the entry. Unless a default is given, the date is
+// guessed from the input directory.
+func promptDate(inputDirectory string, defaultDate string) time.Time {
 	dateStr := time.Now().Format("2006-01-02")
 
 	isGuessedDate := false
-	if match := datePattern.FindString(inputDirectory); match != "" {
+	if defaultDate != "" {
+		dateStr = defaultDate
+	} else if match := datePattern.FindString(inputDirectory); match != "" {
 		dateStr = match
 		isGuessedDate = true
 	}
@@ -498,7 +499,7 @@ func promptDate(inputDirectory string) time.Time {
 		survey.WithValidator(func(ans interface{}) error {
 			s := ans.(string)
 
-			if isTodayAnswer(s) {
+			if authoring.IsToday(s) {
 				return nil
 			}
 
@@ -509,7 +510,7 @@ func promptDate(inputDirectory string) time.Time {
 	)
 	exitOnInterrupt(err)
 
-	if isTodayAnswer(dateStr) {
+	if authoring.IsToday(dateStr) {
 		return time.Now()
 	}
 
@@ -0,0 +1,273 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/bgraf/rueckblick/config"
+	"github.com/bgraf/rueckblick/data"
+	"github.com/bgraf/rueckblick/filesystem"
+	"github.com/bgraf/rueckblick/geotrack"
+	"github.com/bgraf/rueckblick/images"
+	"github.com/bgraf/rueckblick/render"
+	"github.com/spf13/cobra"
+)
+
+// geotagCmd represents the geotag command
+var geotagCmd = &cobra.Command{
+	Use:   "geotag [ENTRY-DIRECTORY]",
+	Short: "Write the positions of gallery photos on the entry's tracks into their EXIF data",
+	Long: `Places the photos of the entry's galleries on the tracks in the entry directory
+like the maps do, using the camera clock of the front matter and gallery. After
+a report of the positions and confirmation, the positions are written into the
+GPS tags of the photo copies, such that other programs show them too. Photos
+with positions of their own are kept unless --overwrite is given.`,
+	Args:         cobra.MaximumNArgs(1),
+	RunE:         runGenGeotag,
+	SilenceUsage: true,
+}
+
+func init() {
+	genCmd.AddCommand(geotagCmd)
+
+	geotagCmd.Flags().BoolP("dry-run", "n", false, "Only report the positions")
+	geotagCmd.Flags().BoolP("yes", "y", false, "Write without asking for confirmation")
+	geotagCmd.Flags().Bool("overwrite", false, "Replace positions already present in photos")
+	geotagCmd.Flags().Duration("tolerance", config.PhotoTolerance(), "Maximal time between a photo and the closest track point")
+}
+
+type geotagAction int
+
+const (
+	geotagWrite geotagAction = iota
+	geotagKeep
+	geotagUnmatched
+	geotagUnsupported
+)
+
+func (a geotagAction) String() string {
+	switch a {
+	case geotagWrite:
+		return "write"
+	case geotagKeep:
+		return "keep own position"
+	case geotagUnmatched:
+		return "no track point"
+	}
+
+	return "unsupported format"
+}
+
+func runGenGeotag(cmd *cobra.Command, args []string) error {
+	dryRun, err := cmd.Flags().GetBool("dry-run")
+	if err != nil {
+		return err
+	}
+
+	yes, err := cmd.Flags().GetBool("yes")
+	if err != nil {
+		return err
+	}
+
+	overwrite, err := cmd.Flags().GetBool("overwrite")
+	if err != nil {
+		return err
+	}
+
+	tolerance := config.PhotoTolerance()
+	if cmd.Flags().Changed("tolerance") {
+		tolerance, err = cmd.Flags().GetDuration("tolerance")
+		if err != nil {
+			return err
+		}
+	}
+
+	entryDirectory := "."
+	if len(args) == 1 {
+		entryDirectory = args[0]
+	}
+
+	entryDirectory = filesystem.Abs(entryDirectory)
+
+	doc, err := loadEntryDocument(entryDirectory)
+	if err != nil {
+		return err
+	}
+
+	points, err := loadEntryTrackPoints(entryDirectory)
+	if err != nil {
+		return err
+	} else if len(points) == 0 {
+		return fmt.Errorf("no track points in '%s'", entryDirectory)
+	}
+
+	positions := data.LocatePhotos(doc, points, tolerance)
+	if len(positions) == 0 {
+		return fmt.Errorf("no photos with timestamps in the galleries of '%s'", doc.Path)
+	}
+
+	actions := make([]geotagAction, len(positions))
+	nWrite := 0
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "PHOTO\tTIME\tΔT\tPOSITION\tDISTANCE\tACTION")
+
+	for i, pos := range positions {
+		img := pos.Image
+
+		switch {
+		case !pos.Located:
+			actions[i] = geotagUnmatched
+		case img.LatLon.IsSome() && !overwrite:
+			actions[i] = geotagKeep
+		case !isJPEG(img.FilePath):
+			actions[i] = geotagUnsupported
+		default:
+			actions[i] = geotagWrite
+			nWrite++
+		}
+
+		position, distance := "-", "-"
+		if pos.Located {
+			position = fmt.Sprintf("%.6f, %.6f", pos.Point.Lat, pos.Point.Lon)
+
+			if img.LatLon.IsSome() {
+				distance = fmt.Sprintf("%.0f m", geotrack.Distance(img.LatLon.Get(), pos.Point))
+			}
+		}
+
+		_, _ = fmt.Fprintf(
+			w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			filepath.Base(img.FilePath),
+			pos.Time.Format("2006-01-02 15:04:05 -07:00"),
+			pos.Delta.Round(time.Second),
+			position,
+			distance,
+			actions[i],
+		)
+	}
+
+	_ = w.Flush()
+
+	fmt.Printf("\n%d of %d photos located, %d to write\n", countLocated(positions), len(positions), nWrite)
+
+	if dryRun || nWrite == 0 {
+		return nil
+	}
+
+	if !yes {
+		confirmed := false
+
+		err := survey.AskOne(
+			&survey.Confirm{
+				Message: fmt.Sprintf("Write positions into %d photos", nWrite),
+				Default: confirmed,
+			},
+			&confirmed,
+		)
+		exitOnInterrupt(err)
+
+		if err != nil {
+			return err
+		}
+
+		if !confirmed {
+			return nil
+		}
+	}
+
+	var errs []error
+
+	for i, pos := range positions {
+		if actions[i] != geotagWrite {
+			continue
+		}
+
+		p := pos.Point
+		p.Time = pos.Time
+
+		if err := images.WriteGPSToFile(pos.Image.FilePath, p); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", pos.Image.FilePath, err))
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("could not write all positions: %w", err)
+	}
+
+	fmt.Printf("wrote %d positions\n", nWrite)
+
+	return nil
+}
+
+// loadEntryDocument loads the document of an entry directory with its galleries.
+func loadEntryDocument(entryDirectory string) (*data.Document, error) {
+	files, err := filepath.Glob(filepath.Join(entryDirectory, "*.md"))
+	if err != nil {
+		return nil, fmt.Errorf("glob: %w", err)
+	}
+
+	if len(files) != 1 {
+		return nil, fmt.Errorf("zero or multiple markdown files in '%s'", entryDirectory)
+	}
+
+	doc, err := data.LoadDocument(files[0], nil)
+	if err != nil {
+		return nil, err
+	}
+
+	toResource := func(original string) (data.Resource, bool) {
+		return data.Resource{URI: "file://" + original}, true
+	}
+
+	if err := render.EmplaceGalleries(doc, toResource, images.ReadEXIFFromFile); err != nil {
+		return nil, data.NewDocumentError(doc.Path, data.StageGallery, err)
+	}
+
+	return doc, nil
+}
+
+// loadEntryTrackPoints loads the points of all track files in the entry directory.
+func loadEntryTrackPoints(entryDirectory string) ([]geotrack.GPXPoint, error) {
+	trackFiles, err := filesystem.GatherFiles([]string{entryDirectory}, data.TrackExtensions())
+	if err != nil {
+		return nil, fmt.Errorf("scanning files: %w", err)
+	}
+
+	var points []geotrack.GPXPoint
+
+	for _, trackFile := range trackFiles {
+		c, err := data.LoadTrackCollection(trackFile)
+		if err != nil {
+			log.Printf("skipping track '%s': %s", trackFile, err)
+			continue
+		}
+
+		points = append(points, c.Points()...)
+	}
+
+	return points, nil
+}
+
+func countLocated(positions []data.PhotoPosition) int {
+	n := 0
+	for _, pos := range positions {
+		if pos.Located {
+			n++
+		}
+	}
+
+	return n
+}
+
+func isJPEG(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".jpg" || ext == ".jpeg"
+}
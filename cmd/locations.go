@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bgraf/rueckblick/config"
+	"github.com/bgraf/rueckblick/data"
+	"github.com/bgraf/rueckblick/filesystem"
+	"github.com/bgraf/rueckblick/geocode"
+	"github.com/bgraf/rueckblick/geotrack"
+	"github.com/bgraf/rueckblick/images"
+	"github.com/spf13/cobra"
+)
+
+// Maximal number of location tags suggested for an entry.
+const maxLocationSuggestions = 5
+
+// locationsCmd represents the locations command
+var locationsCmd = &cobra.Command{
+	Use:   "locations",
+	Short: "Propose missing location tags of entries from their tracks and photos",
+	Long: `Looks up the places nearest to the tracks and geotagged photos of each entry in
+the place extracts configured by geo.places, and lists those places which are not
+yet location tags of the entry. GeoNames dumps like cities500.zip and GeoJSON
+exports of OSM place nodes are supported.`,
+	Args:         cobra.NoArgs,
+	RunE:         runGenLocations,
+	SilenceUsage: true,
+}
+
+func init() {
+	genCmd.AddCommand(locationsCmd)
+}
+
+func runGenLocations(cmd *cobra.Command, args []string) error {
+	if !config.HasJournalDirectory() {
+		return fmt.Errorf("no journal directory configured")
+	}
+
+	ix, err := loadPlaceIndex()
+	if err != nil {
+		return err
+	} else if ix == nil {
+		return fmt.Errorf("no place extracts configured in %s", config.KeyGeoPlaces)
+	}
+
+	store, err := data.NewDefaultStore(filesystem.Abs(config.JournalDirectory()))
+	if err != nil {
+		return err
+	}
+
+	for _, err := range store.Errors {
+		log.Printf("skipping document: %s", err)
+	}
+
+	known := tagsOfCategory(store, "location")
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ENTRY\tLOCATIONS\tSUGGESTED")
+
+	n := 0
+	for _, doc := range store.Documents {
+		points, err := collectPositions(doc.DocumentDirectory())
+		if err != nil {
+			log.Printf("skipping '%s': %s", doc.Path, err)
+			continue
+		}
+
+		var locations []string
+		present := make(map[string]bool)
+		for _, tag := range doc.Tags {
+			if tag.Category == "location" {
+				locations = append(locations, tag.Raw)
+				present[tag.Normalize()] = true
+			}
+		}
+
+		var missing []string
+		for _, name := range suggestLocations(ix, points, known) {
+			if !present[data.NormalizeTagName(name)] {
+				missing = append(missing, name)
+			}
+		}
+
+		if len(missing) == 0 {
+			continue
+		}
+
+		n++
+
+		_, _ = fmt.Fprintf(
+			w, "%s\t%s\t%s\n",
+			filepath.Base(doc.DocumentDirectory()),
+			strings.Join(locations, ", "),
+			strings.Join(missing, ", "),
+		)
+	}
+
+	_ = w.Flush()
+
+	fmt.Printf("\n%d of %d entries with missing location tags\n", n, len(store.Documents))
+
+	return nil
+}
+
+// loadPlaceIndex loads the configured place extracts. It returns nil if none are
+// configured.
+func loadPlaceIndex() (*geocode.Index, error) {
+	var places []geocode.Place
+
+	for _, path := range config.Places() {
+		ps, err := geocode.LoadPlaces(filesystem.Abs(path))
+		if err != nil {
+			return nil, fmt.Errorf("could not load places: %w", err)
+		}
+
+		places = append(places, ps...)
+	}
+
+	if len(places) == 0 {
+		return nil, nil
+	}
+
+	return geocode.NewIndex(places), nil
+}
+
+// tagsOfCategory returns the tags of a category used by the documents of the store.
+func tagsOfCategory(store *data.Store, category string) []string {
+	var tags []string
+	for _, tag := range store.Tags() {
+		if tag.Category == category {
+			tags = append(tags, tag.String())
+		}
+	}
+
+	return tags
+}
+
+// collectPositions returns the points of all tracks and the positions of all geotagged
+// photos in the directory.
+func collectPositions(directory string) ([]geotrack.GPXPoint, error) {
+	points, err := loadEntryTrackPoints(directory)
+	if err != nil {
+		return nil, err
+	}
+
+	photos, err := filesystem.GatherFiles([]string{directory}, []string{".jpeg", ".jpg", ".png", ".heic", ".heif"})
+	if err != nil {
+		return nil, fmt.Errorf("scanning files: %w", err)
+	}
+
+	for _, photo := range photos {
+		exif, err := images.ReadEXIFFromFile(photo)
+		if err == nil && exif.LatLon.IsSome() {
+			points = append(points, exif.LatLon.Get())
+		}
+	}
+
+	return points, nil
+}
+
+// suggestLocations returns the names of the places nearest to the points, spelled like
+// the known tags they correspond to.
+func suggestLocations(ix *geocode.Index, points []geotrack.GPXPoint, known []string) []string {
+	spelling := make(map[string]string)
+	for _, tag := range known {
+		spelling[data.NormalizeTagName(tag)] = tag
+	}
+
+	var names []string
+	for _, s := range ix.Suggest(points, config.PlaceRadius()*1000) {
+		name := s.Name
+		if tag, ok := spelling[data.NormalizeTagName(name)]; ok {
+			name = tag
+		}
+
+		names = append(names, name)
+		if len(names) == maxLocationSuggestions {
+			break
+		}
+	}
+
+	return names
+}
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+
+	"github.com/bgraf/rueckblick/building"
+	"github.com/bgraf/rueckblick/config"
+	"github.com/bgraf/rueckblick/serving"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the build directory and rebuild on changes",
+	Long: `Builds the journal, serves the build directory over HTTP, and watches the
+journal directory. Changed documents and assets trigger an incremental rebuild,
+after which all open pages reload.`,
+	RunE: runServeCmd,
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().StringP("output", "O", "", "Build directory")
+	serveCmd.Flags().StringP("address", "a", "localhost:8080", "Address to listen on")
+	serveCmd.Flags().BoolP("clean", "C", false, "Clean build everything on startup")
+}
+
+func runServeCmd(cmd *cobra.Command, args []string) error {
+	if cmd.Flags().Changed("output") {
+		output, err := cmd.Flags().GetString("output")
+		if err != nil {
+			return err
+		}
+		viper.Set(config.KeyBuildDirectory, output)
+	}
+
+	buildOpts, err := initBuildOptions(cmd)
+	if err != nil {
+		return err
+	}
+
+	// A document failing while it is edited must not hold back updates of other pages.
+	buildOpts.KeepGoing = true
+
+	address, err := cmd.Flags().GetString("address")
+	if err != nil {
+		return err
+	}
+
+	log.Printf("journal directory: %s", buildOpts.JournalDirectory)
+	log.Printf("build directory:   %s", buildOpts.BuildDirectory)
+
+	if err := building.Build(buildOpts); err != nil {
+		// Serve the pages that were written, unless the build failed as a whole.
+		var buildErr *building.BuildError
+		if !errors.As(err, &buildErr) {
+			return err
+		}
+		log.Print(err)
+	}
+
+	// Only the initial build may be a clean build.
+	buildOpts.Clean = false
+
+	notifier := serving.NewChangeNotifier()
+
+	var buildMutex sync.Mutex
+	rebuild := func() {
+		buildMutex.Lock()
+		defer buildMutex.Unlock()
+
+		if err := building.Build(buildOpts); err != nil {
+			// Pages of the other documents were updated nonetheless.
+			var buildErr *building.BuildError
+			if !errors.As(err, &buildErr) {
+				log.Printf("build failed: %s", err)
+				return
+			}
+			log.Print(err)
+		}
+
+		notifier.Notify()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	go func() {
+		err := serving.Watch(
+			ctx,
+			buildOpts.JournalDirectory,
+			[]string{buildOpts.BuildDirectory},
+			rebuild,
+		)
+		if err != nil {
+			log.Printf("watching journal directory failed: %s", err)
+		}
+	}()
+
+	server := &http.Server{
+		Addr:    address,
+		Handler: serving.NewHandler(buildOpts.BuildDirectory, notifier),
+	}
+
+	go func() {
+		<-ctx.Done()
+		_ = server.Shutdown(context.Background())
+	}()
+
+	log.Printf("serving on http://%s/", address)
+
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/bgraf/rueckblick/building"
+	"github.com/bgraf/rueckblick/config"
+	"github.com/bgraf/rueckblick/filesystem"
+	"github.com/bgraf/rueckblick/metacache"
+	"github.com/bgraf/rueckblick/render"
+	"github.com/bgraf/rueckblick/tiles"
+	"github.com/spf13/cobra"
+)
+
+// tilesCmd represents the tiles command
+var tilesCmd = &cobra.Command{
+	Use:   "tiles <file.mbtiles>",
+	Short: "Install map tiles for all tracks into the build directory",
+	Long: `Imports the tiles covering the tracks of all journal entries from an MBTiles
+file into the build directory. Maps show the installed tiles by default, such that
+they work without network access. The configured tile providers remain selectable.`,
+	Args:         cobra.ExactArgs(1),
+	RunE:         runTilesCmd,
+	SilenceUsage: true,
+}
+
+func init() {
+	rootCmd.AddCommand(tilesCmd)
+
+	tilesCmd.Flags().Int("min-zoom", 0, "Lowest zoom level to install")
+	tilesCmd.Flags().Int("max-zoom", 16, "Highest zoom level to install")
+	tilesCmd.Flags().Int("margin", 1, "Number of additional tiles around each track")
+}
+
+func runTilesCmd(cmd *cobra.Command, args []string) error {
+	var opts tiles.ImportOptions
+	var err error
+
+	if opts.MinZoom, err = cmd.Flags().GetInt("min-zoom"); err != nil {
+		return err
+	}
+
+	if opts.MaxZoom, err = cmd.Flags().GetInt("max-zoom"); err != nil {
+		return err
+	}
+
+	if opts.Margin, err = cmd.Flags().GetInt("margin"); err != nil {
+		return err
+	}
+
+	if !config.HasJournalDirectory() || !config.HasBuildDirectory() {
+		return fmt.Errorf("journal and build directories must be configured")
+	}
+
+	journalDirectory := filesystem.Abs(config.JournalDirectory())
+	buildDirectory := filesystem.Abs(config.BuildDirectory())
+
+	if err := filesystem.CreateDirectoryIfNotExists(buildDirectory); err != nil {
+		return fmt.Errorf("could not ensure build directory: %w", err)
+	}
+
+	metadata := metacache.Open(buildDirectory)
+	store, err := building.OpenStore(journalDirectory, buildDirectory, metadata)
+	if err != nil {
+		return fmt.Errorf("could not load store: %w", err)
+	}
+
+	for _, err := range store.Errors {
+		log.Printf("skipping document: %s", err)
+	}
+
+	for _, doc := range store.Documents {
+		for _, m := range render.GeoMaps(doc) {
+			summary, err := metadata.TrackSummary(m.GPXPath)
+			if err != nil {
+				log.Printf("skipping track '%s': %s", m.GPXPath, err)
+				continue
+			}
+
+			if summary.Points == 0 {
+				continue
+			}
+
+			opts.Boxes = append(opts.Boxes, tiles.BoundingBox{
+				MinLat: summary.MinLat,
+				MinLon: summary.MinLon,
+				MaxLat: summary.MaxLat,
+				MaxLon: summary.MaxLon,
+			})
+		}
+	}
+
+	if err := metadata.Save(); err != nil {
+		return fmt.Errorf("could not save metadata cache: %w", err)
+	}
+
+	log.Printf("installing tiles of zoom levels %d to %d for %d tracks", opts.MinZoom, opts.MaxZoom, len(opts.Boxes))
+
+	written, err := tiles.Import(buildDirectory, args[0], opts)
+	if err != nil {
+		return fmt.Errorf("could not import tiles: %w", err)
+	}
+
+	log.Printf("installed %d tiles, rebuild to show them on maps", written)
+
+	return nil
+}
@@ -2,6 +2,8 @@ package config
 
 import (
 	"log"
+	"slices"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -14,8 +16,56 @@ var (
 	KeyMapThreshold     = "geo.mapthreshold"
 	KeyNMEAExtensions   = "geo.extensions.nmea"
 	KeyGPXExtensions    = "geo.extensions.gpx"
+	KeyPhotoTolerance   = "geo.phototolerance"
+	KeyGeoPlaces        = "geo.places"
+	KeyGeoPlaceRadius   = "geo.placeradius"
+	KeyFeedBaseURL      = "feed.baseurl"
+	KeyFeedTitle        = "feed.title"
+	KeyFeedEntries      = "feed.entries"
+	KeyMapTiles         = "map.tiles"
+	KeyMapGlobalTracks  = "map.global.tracks"
+	KeyMapGlobalHeatmap = "map.global.heatmap"
+	KeyMapSimplify      = "map.global.simplify"
+	KeyFields           = "fields"
+	KeyTagCategories    = "tags.categories"
+
+	KeyGalleryJPEGQuality = "generate.gallery.jpeg_quality"
+	KeyGalleryFormat      = "generate.gallery.format"
+	KeyCopyFileExtensions = "generate.entry.copy_files_extensions"
+	KeyVideoExtensions    = "generate.entry.video_extensions"
 )
 
+// TileProvider describes a source of raster map tiles.
+type TileProvider struct {
+	Name        string `mapstructure:"name" json:"name"`
+	URL         string `mapstructure:"url" json:"url"` // Template with {z}, {x}, {y} and optionally {s}
+	Attribution string `mapstructure:"attribution" json:"attribution"`
+	MaxZoom     int    `mapstructure:"maxzoom" json:"maxZoom"`
+}
+
+// FieldSchema declares a custom front matter field.
+type FieldSchema struct {
+	Name     string   `mapstructure:"name" json:"name"`
+	Label    string   `mapstructure:"label" json:"label"` // Shown on entry pages if set
+	Type     string   `mapstructure:"type" json:"type"`   // string, number, integer, date or list, any if empty
+	Required bool     `mapstructure:"required" json:"required"`
+	Values   []string `mapstructure:"values" json:"values"` // Allowed values of strings and list items
+	Min      *float64 `mapstructure:"min" json:"min"`
+	Max      *float64 `mapstructure:"max" json:"max"`
+}
+
+// Types of custom front matter fields.
+var FieldTypes = []string{"", "string", "number", "integer", "date", "list"}
+
+// TagCategory describes a category of tags, e.g., location in `tags: {location: [Berlin]}`.
+type TagCategory struct {
+	Key    string `mapstructure:"key" json:"key"`
+	Label  string `mapstructure:"label" json:"label"` // Heading on the tags page, the key if empty
+	Icon   string `mapstructure:"icon" json:"icon"`   // Icon class shown before tags, e.g., icon-user
+	Order  int    `mapstructure:"order" json:"order"`
+	Prompt string `mapstructure:"prompt" json:"prompt"` // Message of gen entry asking for tags, not asked if empty
+}
+
 type LatLon struct {
 	Lat float64
 	Lon float64
@@ -65,6 +115,40 @@ func DefaultPreviewJPEGQuality() int {
 	return 95
 }
 
+func DefaultGalleryJPEGQuality() int {
+	return 90
+}
+
+func GalleryJPEGQuality() int {
+	if viper.IsSet(KeyGalleryJPEGQuality) {
+		return viper.GetInt(KeyGalleryJPEGQuality)
+	}
+
+	return DefaultGalleryJPEGQuality()
+}
+
+// GalleryFormat returns the extension of the format gallery images are converted to, or an
+// empty string if images keep their format.
+func GalleryFormat() string {
+	return viper.GetString(KeyGalleryFormat)
+}
+
+// CopyFileExtensions returns the extensions of the additional files copied into new
+// entries.
+func CopyFileExtensions() []string {
+	return viper.GetStringSlice(KeyCopyFileExtensions)
+}
+
+// VideoExtensions returns the extensions of copied files which are added to the document
+// as videos.
+func VideoExtensions() []string {
+	return viper.GetStringSlice(KeyVideoExtensions)
+}
+
+func HasHomeCoords() bool {
+	return viper.IsSet(KeyGeoHomeLat) && viper.IsSet(KeyGeoHomeLon)
+}
+
 func HomeCoords() LatLon {
 	if !viper.IsSet(KeyGeoHomeLat) || !viper.IsSet(KeyGeoHomeLon) {
 		log.Fatalf("config: either %s or %s not set", KeyGeoHomeLat, KeyGeoHomeLon)
@@ -88,6 +172,64 @@ func MapThreshold() float64 {
 	return DefaultMapThreshold()
 }
 
+// GlobalMapTracks returns whether the global map shows the tracks of all entries.
+func GlobalMapTracks() bool {
+	return viper.GetBool(KeyMapGlobalTracks)
+}
+
+// GlobalMapHeatmap returns whether the global map offers a heatmap of all track points.
+func GlobalMapHeatmap() bool {
+	return viper.GetBool(KeyMapGlobalHeatmap)
+}
+
+func DefaultMapSimplify() float64 {
+	return 25
+}
+
+// MapSimplify returns the tolerance in meters by which tracks on the global map are
+// simplified.
+func MapSimplify() float64 {
+	if viper.IsSet(KeyMapSimplify) {
+		return viper.GetFloat64(KeyMapSimplify)
+	}
+
+	return DefaultMapSimplify()
+}
+
+func DefaultPhotoTolerance() time.Duration {
+	return 2 * time.Minute
+}
+
+// PhotoTolerance returns the maximal time between a photo and the closest point of a track
+// for the photo to be placed on the track.
+func PhotoTolerance() time.Duration {
+	if viper.IsSet(KeyPhotoTolerance) {
+		return viper.GetDuration(KeyPhotoTolerance)
+	}
+
+	return DefaultPhotoTolerance()
+}
+
+// Places returns the paths of the GeoNames or OSM place extracts used to suggest location
+// tags.
+func Places() []string {
+	return viper.GetStringSlice(KeyGeoPlaces)
+}
+
+func DefaultPlaceRadius() float64 {
+	return 5
+}
+
+// PlaceRadius returns the maximal distance in kilometers of a place suggested for a
+// position.
+func PlaceRadius() float64 {
+	if viper.IsSet(KeyGeoPlaceRadius) {
+		return viper.GetFloat64(KeyGeoPlaceRadius)
+	}
+
+	return DefaultPlaceRadius()
+}
+
 func NMEAExtensions() []string {
 	if viper.IsSet(KeyNMEAExtensions) {
 		return viper.GetStringSlice(KeyNMEAExtensions)
@@ -103,3 +245,135 @@ func GPXExtensions() []string {
 
 	return []string{".gpx"}
 }
+
+// HasFeedBaseURL reports whether an absolute base URL is configured, which is required
+// to generate feeds.
+func HasFeedBaseURL() bool {
+	return viper.IsSet(KeyFeedBaseURL)
+}
+
+func FeedBaseURL() string {
+	return viper.GetString(KeyFeedBaseURL)
+}
+
+func FeedTitle() string {
+	if viper.IsSet(KeyFeedTitle) {
+		return viper.GetString(KeyFeedTitle)
+	}
+
+	return "Rückblick"
+}
+
+func DefaultFeedEntries() int {
+	return 20
+}
+
+// FeedEntries returns the maximal number of entries of a feed.
+func FeedEntries() int {
+	if viper.IsSet(KeyFeedEntries) {
+		n := viper.GetInt(KeyFeedEntries)
+		if n < 0 {
+			log.Fatalf("config: %s must not be negative, got %d", KeyFeedEntries, n)
+		}
+
+		return n
+	}
+
+	return DefaultFeedEntries()
+}
+
+func DefaultTileProviders() []TileProvider {
+	return []TileProvider{
+		{
+			Name:        "OpenStreetMap",
+			URL:         "https://tile.openstreetmap.org/{z}/{x}/{y}.png",
+			Attribution: `&copy; <a href="https://www.openstreetmap.org/copyright">OpenStreetMap</a> contributors`,
+			MaxZoom:     19,
+		},
+	}
+}
+
+// TileProviders returns the configured tile providers. The first one is shown by default,
+// the others can be selected on the map.
+func TileProviders() []TileProvider {
+	if !viper.IsSet(KeyMapTiles) {
+		return DefaultTileProviders()
+	}
+
+	var providers []TileProvider
+	if err := viper.UnmarshalKey(KeyMapTiles, &providers); err != nil {
+		log.Fatalf("config: invalid %s: %s", KeyMapTiles, err)
+	}
+
+	for i := range providers {
+		if providers[i].MaxZoom == 0 {
+			providers[i].MaxZoom = 19
+		}
+	}
+
+	return providers
+}
+
+// FieldSchemas returns the declared custom front matter fields in the order they are shown
+// on entry pages.
+func FieldSchemas() []FieldSchema {
+	var schemas []FieldSchema
+	if err := viper.UnmarshalKey(KeyFields, &schemas); err != nil {
+		log.Fatalf("config: invalid %s: %s", KeyFields, err)
+	}
+
+	for _, schema := range schemas {
+		if schema.Name == "" {
+			log.Fatalf("config: field without name in %s", KeyFields)
+		}
+
+		if !slices.Contains(FieldTypes, schema.Type) {
+			log.Fatalf("config: field '%s' has unknown type '%s'", schema.Name, schema.Type)
+		}
+	}
+
+	return schemas
+}
+
+func DefaultTagCategories() []TagCategory {
+	return []TagCategory{
+		{Key: "location", Label: "Orte", Icon: "icon-map-pin-line", Order: 10, Prompt: "Location"},
+		{Key: "people", Label: "Personen", Icon: "icon-user", Order: 20, Prompt: "People"},
+		{Key: "general", Label: "Andere", Order: 30, Prompt: "Tag"},
+	}
+}
+
+// TagCategories returns the configured tag categories sorted by their order. Tags of
+// other categories are shown nonetheless. The category period of the tags derived from
+// periods.yaml is built in.
+func TagCategories() []TagCategory {
+	if !viper.IsSet(KeyTagCategories) {
+		return DefaultTagCategories()
+	}
+
+	var categories []TagCategory
+	if err := viper.UnmarshalKey(KeyTagCategories, &categories); err != nil {
+		log.Fatalf("config: invalid %s: %s", KeyTagCategories, err)
+	}
+
+	keys := make(map[string]bool)
+	for i, category := range categories {
+		if category.Key == "" {
+			log.Fatalf("config: tag category without key in %s", KeyTagCategories)
+		} else if keys[category.Key] {
+			log.Fatalf("config: duplicate tag category '%s'", category.Key)
+		}
+
+		keys[category.Key] = true
+
+		if category.Label == "" {
+			categories[i].Label = category.Key
+		}
+	}
+
+	slices.SortStableFunc(categories, func(a, b TagCategory) int {
+		return a.Order - b.Order
+	})
+
+	return categories
+}
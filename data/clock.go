@@ -0,0 +1,58 @@
+package data
+
+import (
+	"fmt"
+	"time"
+)
+
+// CameraClock describes how the clock of a camera relates to the actual time, such that
+// photos can be placed on tracks recorded in UTC.
+type CameraClock struct {
+	// Time zone the camera clock was set to. Nil denotes the local time zone. Ignored for
+	// photos whose EXIF data records the offset from UTC.
+	Location *time.Location
+
+	// Deviation of the camera clock, which is added to the time of a photo to obtain the
+	// actual time.
+	Offset time.Duration
+}
+
+// With returns a copy of the clock with the time zone and offset replaced by the given
+// ones, unless they are empty. The time zone is an IANA name such as `Europe/Berlin`, the
+// offset a duration such as `-1m30s`.
+func (c CameraClock) With(timezone string, offset string) (CameraClock, error) {
+	if timezone != "" {
+		location, err := time.LoadLocation(timezone)
+		if err != nil {
+			return c, fmt.Errorf("invalid time zone '%s': %w", timezone, err)
+		}
+		c.Location = location
+	}
+
+	if offset != "" {
+		d, err := time.ParseDuration(offset)
+		if err != nil {
+			return c, fmt.Errorf("invalid clock offset '%s': %w", offset, err)
+		}
+		c.Offset = d
+	}
+
+	return c, nil
+}
+
+// PhotoTime returns the actual time the image was taken, if it has a timestamp.
+func (c CameraClock) PhotoTime(img Image) (time.Time, bool) {
+	if img.Timestamp.IsNone() {
+		return time.Time{}, false
+	}
+
+	t := img.Timestamp.Get()
+
+	// Without a recorded offset, the timestamp is the wall clock time of the camera, which
+	// was read in the local time zone.
+	if img.TimeOffset.IsNone() && c.Location != nil {
+		t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), c.Location)
+	}
+
+	return t.Add(c.Offset), true
+}
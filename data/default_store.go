@@ -4,6 +4,8 @@ import (
 	"fmt"
 )
 
+// NewDefaultStore loads the store such that resources reference the original files via
+// `file://` URIs.
 func NewDefaultStore(journalDirectory string) (*Store, error) {
 	storeOpts := &StoreOptions{
 		RenderImagePath: func(doc *Document, srcPath string) (Resource, bool) {
@@ -14,6 +16,12 @@ func NewDefaultStore(journalDirectory string) (*Store, error) {
 		},
 	}
 
+	return NewDefaultStoreWithOptions(journalDirectory, storeOpts)
+}
+
+// NewDefaultStoreWithOptions loads the store using the given options and sorts its documents
+// and tags.
+func NewDefaultStoreWithOptions(journalDirectory string, storeOpts *StoreOptions) (*Store, error) {
 	store, err := NewStore(
 		journalDirectory,
 		storeOpts,
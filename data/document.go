@@ -21,15 +21,17 @@ type Image struct {
 	Resource      Resource
 	ThumbResource Resource
 	Timestamp     option.Option[time.Time]
+	TimeOffset    option.Option[time.Duration] // Offset from UTC recorded by the camera
 	LatLon        option.Option[geotrack.GPXPoint]
 }
 
 type Gallery struct {
 	ElementID string
 	Images    []Image
+	Clock     CameraClock
 }
 
-func (g *Gallery) AppendImage(res Resource, thumbRes Resource, filePath string, timestamp option.Option[time.Time], latLon option.Option[geotrack.GPXPoint]) {
+func (g *Gallery) AppendImage(res Resource, thumbRes Resource, filePath string, timestamp option.Option[time.Time], timeOffset option.Option[time.Duration], latLon option.Option[geotrack.GPXPoint]) {
 	g.Images = append(
 		g.Images,
 		Image{
@@ -37,6 +39,7 @@ func (g *Gallery) AppendImage(res Resource, thumbRes Resource, filePath string,
 			Resource:      res,
 			ThumbResource: thumbRes,
 			Timestamp:     timestamp,
+			TimeOffset:    timeOffset,
 			LatLon:        latLon,
 		},
 	)
@@ -50,10 +53,15 @@ type Document struct {
 	Tags            []Tag
 	Periods         []Period
 	Date            time.Time
+	TimeOfDay       option.Option[time.Duration] // Optional time since midnight of Date
+	Author          string
 	Abstract        string
 	Preview         string
+	PreviewResource Resource
 	Galleries       []*Gallery
+	Clock           CameraClock // Clock of the camera the photos of the galleries were taken with
 	Maps            []GXPMap
+	Fields          map[string]*Field // Custom front matter fields by key
 	HasFrontMatter  bool
 	IsHtmlProcessed bool
 }
@@ -62,6 +70,48 @@ func (doc *Document) DocumentDirectory() string {
 	return path.Dir(doc.Path)
 }
 
+func (doc *Document) HasAuthor() bool {
+	return len(doc.Author) > 0
+}
+
+// Field returns the custom front matter field, or nil if the document lacks it.
+func (doc *Document) Field(key string) *Field {
+	return doc.Fields[key]
+}
+
+func (doc *Document) HasTime() bool {
+	return doc.TimeOfDay.IsSome()
+}
+
+// TimeDisplay returns the time of day formatted as `15:04`, or an empty string if the
+// document has no time.
+func (doc *Document) TimeDisplay() string {
+	if doc.TimeOfDay.IsNone() {
+		return ""
+	}
+
+	return doc.Date.Add(doc.TimeOfDay.Get()).Format("15:04")
+}
+
+// Before reports whether the document precedes the other one chronologically. Documents on
+// the same day are ordered by their time of day, where documents without time come first,
+// and by their paths.
+func (doc *Document) Before(other *Document) bool {
+	if !doc.Date.Equal(other.Date) {
+		return doc.Date.Before(other.Date)
+	}
+
+	if doc.TimeOfDay.IsSome() != other.TimeOfDay.IsSome() {
+		return other.TimeOfDay.IsSome()
+	}
+
+	if doc.TimeOfDay.IsSome() && doc.TimeOfDay.Get() != other.TimeOfDay.Get() {
+		return doc.TimeOfDay.Get() < other.TimeOfDay.Get()
+	}
+
+	return doc.Path < other.Path
+}
+
 func (doc *Document) HasAbstract() bool {
 	return len(doc.Abstract) > 0
 }
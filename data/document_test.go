@@ -0,0 +1,57 @@
+package data
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/bgraf/rueckblick/option"
+)
+
+func TestDocumentBefore(t *testing.T) {
+	day := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	docs := []*Document{
+		{Path: "e.md", Date: day.AddDate(0, 0, 1), TimeOfDay: option.None[time.Duration]()},
+		{Path: "d.md", Date: day, TimeOfDay: option.Some(18 * time.Hour)},
+		{Path: "b.md", Date: day, TimeOfDay: option.Some(9 * time.Hour)},
+		{Path: "c.md", Date: day, TimeOfDay: option.Some(9 * time.Hour)},
+		{Path: "z.md", Date: day, TimeOfDay: option.None[time.Duration]()},
+		{Path: "a.md", Date: day, TimeOfDay: option.None[time.Duration]()},
+	}
+
+	sort.Slice(docs, func(i, j int) bool {
+		return docs[i].Before(docs[j])
+	})
+
+	want := []string{"a.md", "z.md", "b.md", "c.md", "d.md", "e.md"}
+	for i, doc := range docs {
+		if doc.Path != want[i] {
+			t.Errorf("position %d: got %s, want %s", i, doc.Path, want[i])
+		}
+	}
+}
+
+func TestParseTimeOfDay(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"09:30", 9*time.Hour + 30*time.Minute, false},
+		{"23:59:30", 23*time.Hour + 59*time.Minute + 30*time.Second, false},
+		{"9.30", 0, true},
+		{"25:00", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseTimeOfDay(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: unexpected error %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package data
+
+import "fmt"
+
+// Stage names a step in processing a document.
+type Stage string
+
+const (
+	StageFrontMatter Stage = "front matter"
+	StageMarkdown    Stage = "markdown"
+	StageGallery     Stage = "gallery"
+	StageGPX         Stage = "gpx"
+	StageVideo       Stage = "video"
+	StageTemplate    Stage = "template"
+	StageOutput      Stage = "output"
+)
+
+// DocumentError reports that processing the document at Path failed in the given stage.
+type DocumentError struct {
+	Path  string
+	Stage Stage
+	Err   error
+}
+
+func NewDocumentError(path string, stage Stage, err error) *DocumentError {
+	return &DocumentError{Path: path, Stage: stage, Err: err}
+}
+
+func (e *DocumentError) Error() string {
+	return fmt.Sprintf("%s: %s: %s", e.Path, e.Stage, e.Err)
+}
+
+func (e *DocumentError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,198 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bgraf/rueckblick/config"
+)
+
+// FieldKind is the kind of value of a custom front matter field.
+type FieldKind string
+
+const (
+	FieldNull   FieldKind = "null"
+	FieldString FieldKind = "string"
+	FieldNumber FieldKind = "number"
+	FieldBool   FieldKind = "bool"
+	FieldDate   FieldKind = "date"
+	FieldList   FieldKind = "list"
+	FieldMap    FieldKind = "map"
+)
+
+// Field is the value of a front matter key unknown to rueckblick, e.g., `weather: sunny`.
+type Field struct {
+	value any
+}
+
+func NewField(value any) *Field {
+	return &Field{value: value}
+}
+
+// Value returns the value as decoded from YAML.
+func (f *Field) Value() any {
+	return f.value
+}
+
+func (f *Field) Kind() FieldKind {
+	switch f.value.(type) {
+	case nil:
+		return FieldNull
+	case string:
+		if _, ok := f.Date(); ok {
+			return FieldDate
+		}
+		return FieldString
+	case int, int64, uint64, float64:
+		return FieldNumber
+	case bool:
+		return FieldBool
+	case time.Time:
+		return FieldDate
+	case []any:
+		return FieldList
+	default:
+		return FieldMap
+	}
+}
+
+// String formats the value for display. Dates are formatted as `2006-01-02` and list
+// items are separated by commas.
+func (f *Field) String() string {
+	switch v := f.value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		return v.Format("2006-01-02")
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range f.List() {
+			items[i] = item.String()
+		}
+		return strings.Join(items, ", ")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Number returns the value if it is a number.
+func (f *Field) Number() (float64, bool) {
+	switch v := f.value.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+
+	return 0, false
+}
+
+// Date returns the value if it is a date given as `2006-01-02`.
+func (f *Field) Date() (time.Time, bool) {
+	switch v := f.value.(type) {
+	case time.Time:
+		return v, true
+	case string:
+		date, err := time.Parse("2006-01-02", v)
+		return date, err == nil
+	}
+
+	return time.Time{}, false
+}
+
+// List returns the items if the value is a list, or the value as single item otherwise.
+func (f *Field) List() []*Field {
+	switch v := f.value.(type) {
+	case nil:
+		return nil
+	case []any:
+		items := make([]*Field, len(v))
+		for i, item := range v {
+			items[i] = NewField(item)
+		}
+		return items
+	}
+
+	return []*Field{f}
+}
+
+// ValidateFields checks the fields against the declared schemas.
+func ValidateFields(fields map[string]*Field, schemas []config.FieldSchema) error {
+	var errs []error
+
+	for _, schema := range schemas {
+		field, ok := fields[schema.Name]
+		if !ok || field.Kind() == FieldNull {
+			if schema.Required {
+				errs = append(errs, fmt.Errorf("field '%s': missing", schema.Name))
+			}
+			continue
+		}
+
+		if err := validateField(field, schema); err != nil {
+			errs = append(errs, fmt.Errorf("field '%s': %w", schema.Name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func validateField(field *Field, schema config.FieldSchema) error {
+	switch schema.Type {
+	case "string":
+		if _, ok := field.value.(string); !ok {
+			return fmt.Errorf("want string, got %s", field.Kind())
+		}
+
+	case "number", "integer":
+		n, ok := field.Number()
+		if !ok {
+			return fmt.Errorf("want %s, got %s", schema.Type, field.Kind())
+		}
+
+		if schema.Type == "integer" && n != math.Trunc(n) {
+			return fmt.Errorf("want integer, got %s", field)
+		}
+
+		if schema.Min != nil && n < *schema.Min {
+			return fmt.Errorf("%s is less than %g", field, *schema.Min)
+		}
+
+		if schema.Max != nil && n > *schema.Max {
+			return fmt.Errorf("%s is greater than %g", field, *schema.Max)
+		}
+
+	case "date":
+		if _, ok := field.Date(); !ok {
+			return fmt.Errorf("want date YYYY-MM-DD, got '%s'", field)
+		}
+
+	case "list":
+		if field.Kind() != FieldList {
+			return fmt.Errorf("want list, got %s", field.Kind())
+		}
+	}
+
+	if len(schema.Values) > 0 {
+		for _, item := range field.List() {
+			if !slices.Contains(schema.Values, item.String()) {
+				return fmt.Errorf("'%s' is not one of %s", item, strings.Join(schema.Values, ", "))
+			}
+		}
+	}
+
+	return nil
+}
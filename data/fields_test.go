@@ -0,0 +1,106 @@
+package data
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bgraf/rueckblick/config"
+)
+
+func TestReadFrontMatterFields(t *testing.T) {
+	source := []byte(`---
+title: Walk
+date: 2023-04-02
+author: Anna
+weather: sunny
+mood: 4
+cost: 12.5
+visited: 2023-04-01
+companions: [Anna, Ben]
+---
+Body
+`)
+
+	var doc Document
+	if _, err := ReadFrontMatter(&doc, source); err != nil {
+		t.Fatal(err)
+	}
+
+	if doc.Author != "Anna" {
+		t.Errorf("author '%s', want 'Anna'", doc.Author)
+	}
+
+	tests := []struct {
+		key    string
+		kind   FieldKind
+		string string
+	}{
+		{"weather", FieldString, "sunny"},
+		{"mood", FieldNumber, "4"},
+		{"cost", FieldNumber, "12.5"},
+		{"visited", FieldDate, "2023-04-01"},
+		{"companions", FieldList, "Anna, Ben"},
+	}
+
+	if len(doc.Fields) != len(tests) {
+		t.Errorf("got %d fields, want %d", len(doc.Fields), len(tests))
+	}
+
+	for _, tt := range tests {
+		field := doc.Field(tt.key)
+		if field == nil {
+			t.Errorf("%s: missing", tt.key)
+			continue
+		}
+
+		if field.Kind() != tt.kind || field.String() != tt.string {
+			t.Errorf("%s: got %s '%s', want %s '%s'", tt.key, field.Kind(), field, tt.kind, tt.string)
+		}
+	}
+
+	if n, ok := doc.Field("cost").Number(); !ok || n != 12.5 {
+		t.Errorf("cost: got %f, %v", n, ok)
+	}
+}
+
+func TestValidateFields(t *testing.T) {
+	one, five := 1.0, 5.0
+
+	schemas := []config.FieldSchema{
+		{Name: "weather", Type: "string", Values: []string{"sunny", "rainy"}},
+		{Name: "mood", Type: "integer", Min: &one, Max: &five, Required: true},
+		{Name: "visited", Type: "date"},
+		{Name: "companions", Type: "list"},
+	}
+
+	tests := []struct {
+		fields  map[string]any
+		wantErr string
+	}{
+		{map[string]any{"mood": 3, "weather": "sunny", "visited": "2023-04-01", "companions": []any{"Anna"}}, ""},
+		{map[string]any{"mood": 3, "other": true}, ""},
+		{map[string]any{}, "field 'mood': missing"},
+		{map[string]any{"mood": 6}, "6 is greater than 5"},
+		{map[string]any{"mood": 2.5}, "want integer"},
+		{map[string]any{"mood": "good"}, "want integer, got string"},
+		{map[string]any{"mood": 3, "weather": "foggy"}, "'foggy' is not one of sunny, rainy"},
+		{map[string]any{"mood": 3, "visited": "yesterday"}, "want date"},
+		{map[string]any{"mood": 3, "companions": "Anna"}, "want list, got string"},
+	}
+
+	for _, tt := range tests {
+		fields := make(map[string]*Field)
+		for key, value := range tt.fields {
+			fields[key] = NewField(value)
+		}
+
+		err := ValidateFields(fields, schemas)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%v: unexpected error %s", tt.fields, err)
+			}
+		} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%v: got error %v, want %s", tt.fields, err, tt.wantErr)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/bgraf/rueckblick/option"
 	"github.com/yuin/goldmark/util"
 	"gopkg.in/yaml.v2"
 )
@@ -12,10 +13,21 @@ import (
 type FrontMatter struct {
 	Title    string              `yaml:"title"`
 	Date     YamlDate            `yaml:"date"`
+	Time     string              `yaml:"time,omitempty"`
 	Author   string              `yaml:"author"`
 	Preview  string              `yaml:"preview,omitempty"`
 	Abstract string              `yaml:"abstract,omitempty"`
 	Tags     map[string][]string `yaml:"tags,omitempty"`
+	Camera   FrontMatterCamera   `yaml:"camera,omitempty"`
+
+	// Keys unknown to rueckblick, see Document.Fields.
+	Fields map[string]any `yaml:",inline"`
+}
+
+// FrontMatterCamera configures the clock of the camera photos were taken with.
+type FrontMatterCamera struct {
+	Timezone string `yaml:"timezone,omitempty"`
+	Offset   string `yaml:"offset,omitempty"`
 }
 
 func ReadFrontMatter(doc *Document, source []byte) ([]byte, error) {
@@ -33,9 +45,29 @@ func ReadFrontMatter(doc *Document, source []byte) ([]byte, error) {
 
 	doc.Title = fm.Title
 	doc.Date = time.Time(fm.Date)
+
+	doc.TimeOfDay = option.None[time.Duration]()
+	if fm.Time != "" {
+		timeOfDay, err := ParseTimeOfDay(fm.Time)
+		if err != nil {
+			return source, err
+		}
+		doc.TimeOfDay = option.Some(timeOfDay)
+	}
+	doc.Author = fm.Author
 	doc.Abstract = fm.Abstract
 	doc.Preview = fm.Preview
 
+	doc.Fields = make(map[string]*Field, len(fm.Fields))
+	for key, value := range fm.Fields {
+		doc.Fields[key] = NewField(value)
+	}
+
+	doc.Clock, err = CameraClock{}.With(fm.Camera.Timezone, fm.Camera.Offset)
+	if err != nil {
+		return source, err
+	}
+
 	for category, names := range fm.Tags {
 		for _, name := range names {
 			doc.Tags = append(
@@ -53,6 +85,18 @@ func ReadFrontMatter(doc *Document, source []byte) ([]byte, error) {
 	return mdSource, nil
 }
 
+// ParseTimeOfDay parses a time of day given as `15:04` or `15:04:05` into the duration
+// since midnight.
+func ParseTimeOfDay(s string) (time.Duration, error) {
+	for _, layout := range []string{"15:04", "15:04:05"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.Sub(time.Date(0, 1, 1, 0, 0, 0, 0, time.UTC)), nil
+		}
+	}
+
+	return 0, fmt.Errorf("invalid time of day '%s'", s)
+}
+
 type YamlDate time.Time
 
 func (t *YamlDate) UnmarshalYAML(unmarshal func(interface{}) error) error {
@@ -5,7 +5,6 @@ import (
 	"errors"
 	"fmt"
 	"io/fs"
-	"log"
 	"os"
 	"path/filepath"
 	"sort"
@@ -15,6 +14,7 @@ import (
 	"github.com/PuerkitoBio/goquery"
 	"github.com/bgraf/rueckblick/config"
 	"github.com/bgraf/rueckblick/filesystem"
+	"github.com/bgraf/rueckblick/images"
 	"github.com/bgraf/rueckblick/util/dates"
 	"github.com/yuin/goldmark"
 	"github.com/yuin/goldmark/parser"
@@ -23,6 +23,9 @@ import (
 
 type StoreOptions struct {
 	RenderImagePath func(doc *Document, srcPath string) (Resource, bool)
+
+	// ReadEXIF reads the EXIF data of gallery images. Defaults to images.ReadEXIFFromFile.
+	ReadEXIF func(path string) (images.EXIFData, error)
 }
 
 type Store struct {
@@ -32,6 +35,10 @@ type Store struct {
 	tagByNormalizedName map[string]Tag
 	tags                []Tag
 	Options             *StoreOptions
+	fieldSchemas        []config.FieldSchema
+
+	// Errors of documents that could not be loaded and are thus missing from Documents.
+	Errors []*DocumentError
 }
 
 func NewStore(rootDirectory string, options *StoreOptions) (*Store, error) {
@@ -39,6 +46,7 @@ func NewStore(rootDirectory string, options *StoreOptions) (*Store, error) {
 		RootDirectory:       rootDirectory,
 		tagByNormalizedName: make(map[string]Tag),
 		Options:             options,
+		fieldSchemas:        config.FieldSchemas(),
 	}
 
 	var err error
@@ -68,12 +76,15 @@ func NewStore(rootDirectory string, options *StoreOptions) (*Store, error) {
 	return store, nil
 }
 
+// SortDocumentsByDate sorts the documents from the most recent to the oldest one, see
+// `Document.Before` for the order of documents on the same day.
 func (s *Store) SortDocumentsByDate() {
-	sort.Slice(s.Documents, func(i, j int) bool {
-		return s.Documents[i].Date.After(s.Documents[j].Date)
+	sort.SliceStable(s.Documents, func(i, j int) bool {
+		return s.Documents[j].Before(s.Documents[i])
 	})
 }
 
+// DocumentsOnDate returns the documents of the given day in chronological order.
 func (s *Store) DocumentsOnDate(t time.Time) []*Document {
 	var docs []*Document
 
@@ -83,6 +94,10 @@ func (s *Store) DocumentsOnDate(t time.Time) []*Document {
 		}
 	}
 
+	sort.SliceStable(docs, func(i, j int) bool {
+		return docs[i].Before(docs[j])
+	})
+
 	return docs
 }
 
@@ -154,6 +169,11 @@ func (s *Store) loadDocuments(rootDirectory string) ([]*Document, error) {
 
 		doc, err := s.loadDocument(path)
 		if err != nil {
+			var docErr *DocumentError
+			if errors.As(err, &docErr) {
+				s.Errors = append(s.Errors, docErr)
+				return nil
+			}
 			return err
 		}
 
@@ -178,6 +198,12 @@ func (s *Store) loadDocuments(rootDirectory string) ([]*Document, error) {
 	return docs, nil
 }
 
+// LoadDocument loads a single document outside of a store, e.g., to work on one entry.
+func LoadDocument(path string, options *StoreOptions) (*Document, error) {
+	s := &Store{Options: options, fieldSchemas: config.FieldSchemas()}
+	return s.loadDocument(path)
+}
+
 func (s *Store) loadDocument(path string) (*Document, error) {
 	sourceText, err := os.ReadFile(path)
 	if err != nil {
@@ -191,7 +217,15 @@ func (s *Store) loadDocument(path string) (*Document, error) {
 
 	sourceText, err = ReadFrontMatter(doc, sourceText)
 	if err != nil {
-		return nil, fmt.Errorf("could not read front matter: %w", err)
+		return nil, NewDocumentError(path, StageFrontMatter, err)
+	}
+
+	if err := ValidateFields(doc.Fields, s.fieldSchemas); err != nil {
+		return nil, NewDocumentError(path, StageFrontMatter, err)
+	}
+
+	if doc.HasPreview() && s.Options != nil && s.Options.RenderImagePath != nil {
+		doc.PreviewResource, _ = s.Options.RenderImagePath(doc, doc.PreviewAbsolutePath())
 	}
 
 	gmark := goldmark.New(goldmark.WithRendererOptions(html.WithUnsafe()))
@@ -202,12 +236,12 @@ func (s *Store) loadDocument(path string) (*Document, error) {
 
 	err = gmark.Convert(sourceText, &buffer, parser.WithContext(pc))
 	if err != nil {
-		log.Fatalf("gmark.Convert: %s", err)
+		return nil, NewDocumentError(path, StageMarkdown, err)
 	}
 
 	doc.HTML, err = goquery.NewDocumentFromReader(&buffer)
 	if err != nil {
-		return nil, fmt.Errorf("could not parse HTML: %w", err)
+		return nil, NewDocumentError(path, StageMarkdown, fmt.Errorf("could not parse HTML: %w", err))
 	}
 
 	return doc, nil
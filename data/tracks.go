@@ -1,7 +1,8 @@
 package data
 
 import (
-	"fmt"
+	"log"
+	"math"
 	"path"
 	"slices"
 	"strings"
@@ -9,7 +10,7 @@ import (
 
 	"github.com/bgraf/rueckblick/config"
 	"github.com/bgraf/rueckblick/geotrack"
-	"github.com/jftuga/geodist"
+	"github.com/bgraf/rueckblick/option"
 )
 
 type GPXLocatedImage struct {
@@ -18,95 +19,196 @@ type GPXLocatedImage struct {
 	LatLng   geotrack.GPXPoint
 }
 
-func LoadTrack(trackFilePath string) (points []geotrack.GPXPoint, err error) {
+// TrackExtensions returns the extensions of all supported track files.
+func TrackExtensions() []string {
+	extensions := slices.Concat(config.GPXExtensions(), config.NMEAExtensions(), geotrack.Extensions())
+	slices.Sort(extensions)
+
+	return slices.Compact(extensions)
+}
+
+// LoadTrackCollection loads the tracks, routes and waypoints of a track file. The
+// configured GPX and NMEA extensions take precedence over the detection of the format.
+func LoadTrackCollection(trackFilePath string) (c *geotrack.Collection, err error) {
 	ext := strings.ToLower(path.Ext(trackFilePath))
 	if slices.Contains(config.GPXExtensions(), ext) {
-		points, err = geotrack.LoadGPXTrack(trackFilePath)
+		c, err = geotrack.LoadGPX(trackFilePath)
 	} else if slices.Contains(config.NMEAExtensions(), ext) {
-		points, err = geotrack.LoadNMEATrack(trackFilePath)
+		c, err = geotrack.LoadNMEA(trackFilePath)
 	} else {
-		return nil, fmt.Errorf("unknown track extension '%s'", ext)
+		c, err = geotrack.Load(trackFilePath)
 	}
 
 	if err != nil {
-		return
+		return nil, err
 	}
 
-	return
+	if c.Skipped > 0 {
+		log.Printf("skipped %d unreadable entries of track '%s'", c.Skipped, trackFilePath)
+	}
+
+	return c, nil
+}
+
+// LoadTrack loads the points of all tracks of a track file.
+func LoadTrack(trackFilePath string) (points []geotrack.GPXPoint, err error) {
+	c, err := LoadTrackCollection(trackFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Points(), nil
 }
 
 // LoadTrackWithImages loads a track file from the given file path and correlates the documents images with
 // the track's points.
-func LoadTrackWithImages(doc *Document, trackFilePath string) (points []geotrack.GPXPoint, images []GPXLocatedImage, err error) {
-	points, err = LoadTrack(trackFilePath)
+func LoadTrackWithImages(doc *Document, trackFilePath string) (c *geotrack.Collection, images []GPXLocatedImage, err error) {
+	c, err = LoadTrackCollection(trackFilePath)
 	if err != nil {
 		return
 	}
 
-	images = findMatchingImages(doc, points)
+	images = findMatchingImages(doc, c.Points())
 	return
 }
 
 func findMatchingImages(doc *Document, points []geotrack.GPXPoint) []GPXLocatedImage {
 	var locatedImages []GPXLocatedImage
 
+	for _, pos := range LocatePhotos(doc, points, config.PhotoTolerance()) {
+		if !pos.Located {
+			continue
+		}
+
+		locatedImages = append(
+			locatedImages,
+			GPXLocatedImage{
+				URI:      pos.Image.Resource.URI,
+				ThumbURI: pos.Image.ThumbResource.URI,
+				LatLng:   geotrack.GPXPoint{Lat: pos.Point.Lat, Lon: pos.Point.Lon},
+			},
+		)
+	}
+
+	return locatedImages
+}
+
+// PhotoPosition is the result of placing an image on a track.
+type PhotoPosition struct {
+	Image   Image
+	Time    time.Time         // Actual time the image was taken
+	Delta   time.Duration     // Time between the image and the closest point of the track
+	Point   geotrack.GPXPoint // Position on the track, if located
+	Located bool
+}
+
+// LocatePhotos places the images with timestamps of all galleries of the document on the
+// track given by its points. Images are located if the track has a point within the
+// tolerance of their time.
+func LocatePhotos(doc *Document, points []geotrack.GPXPoint, tolerance time.Duration) []PhotoPosition {
+	var positions []PhotoPosition
+
+	points = timedPoints(points)
+
 	for _, gal := range doc.Galleries {
 		for _, img := range gal.Images {
-			if img.Timestamp.IsNone() {
+			t, ok := gal.Clock.PhotoTime(img)
+			if !ok {
 				continue
 			}
-			targetTime := img.Timestamp.Get()
-			nearest, duration := findClosestPointInTime(points, targetTime)
-
-			if duration < 120*time.Second {
-				if img.LatLon.IsSome() {
-					fmt.Printf("Found image with own coords: %#v %#v\n", img.LatLon.Get(), nearest)
-
-					_, dkm := geodist.HaversineDistance(
-						geodist.Coord{
-							Lat: img.LatLon.Get().Lat,
-							Lon: img.LatLon.Get().Lon,
-						},
-						geodist.Coord{
-							Lat: nearest.Lat,
-							Lon: nearest.Lon,
-						},
-					)
-					fmt.Printf("distance: %f\n", dkm)
-
-				}
-				locatedImages = append(
-					locatedImages,
-					GPXLocatedImage{
-						URI:      img.Resource.URI,
-						ThumbURI: img.ThumbResource.URI,
-						LatLng:   geotrack.GPXPoint{Lat: nearest.Lat, Lon: nearest.Lon},
-					},
-				)
-			}
+
+			p, delta, ok := locateTime(points, t, tolerance)
+
+			positions = append(positions, PhotoPosition{
+				Image:   img,
+				Time:    t,
+				Delta:   delta,
+				Point:   p,
+				Located: ok,
+			})
 		}
 	}
 
-	return locatedImages
+	return positions
+}
+
+// timedPoints returns the points with timestamps ordered by time.
+func timedPoints(points []geotrack.GPXPoint) []geotrack.GPXPoint {
+	timed := make([]geotrack.GPXPoint, 0, len(points))
+	for _, p := range points {
+		if !p.Time.IsZero() {
+			timed = append(timed, p)
+		}
+	}
+
+	slices.SortStableFunc(timed, func(p, q geotrack.GPXPoint) int {
+		return p.Time.Compare(q.Time)
+	})
+
+	return timed
 }
 
-func findClosestPointInTime(points []geotrack.GPXPoint, targetTime time.Time) (geotrack.GPXPoint, time.Duration) {
-	absDuration := func(d time.Duration) time.Duration {
-		if d < 0 {
-			return -d
+// locateTime returns the position at the given time on a track given by its points
+// ordered by time. Between two points that are both within the tolerance, the position is
+// interpolated. Otherwise, the point within the tolerance is used, if any. The returned
+// duration is the time to the closest point.
+func locateTime(points []geotrack.GPXPoint, t time.Time, tolerance time.Duration) (geotrack.GPXPoint, time.Duration, bool) {
+	// Index of the first point not before t.
+	i, _ := slices.BinarySearchFunc(points, t, func(p geotrack.GPXPoint, t time.Time) int {
+		return p.Time.Compare(t)
+	})
+
+	var before, after option.Option[geotrack.GPXPoint]
+	if i > 0 {
+		before = option.Some(points[i-1])
+	}
+	if i < len(points) {
+		after = option.Some(points[i])
+	}
+
+	delta := time.Duration(math.MaxInt64)
+	if before.IsSome() {
+		delta = t.Sub(before.Get().Time)
+	}
+	if after.IsSome() {
+		delta = min(delta, after.Get().Time.Sub(t))
+	}
+
+	withinTolerance := func(p option.Option[geotrack.GPXPoint]) bool {
+		if p.IsNone() {
+			return false
 		}
-		return d
+
+		d := t.Sub(p.Get().Time)
+		return d <= tolerance && d >= -tolerance
 	}
 
-	durBest := time.Duration(1 << 62)
-	iBest := 0
-	for i := range points {
-		durI := absDuration(targetTime.Sub(points[i].Time))
-		if durI <= durBest {
-			durBest = durI
-			iBest = i
+	switch {
+	case after.IsSome() && after.Get().Time.Equal(t):
+		return after.Get(), 0, true
+
+	case withinTolerance(before) && withinTolerance(after):
+		p, q := before.Get(), after.Get()
+		f := float64(t.Sub(p.Time)) / float64(q.Time.Sub(p.Time))
+
+		located := geotrack.GPXPoint{
+			Lat:  p.Lat + f*(q.Lat-p.Lat),
+			Lon:  p.Lon + f*(q.Lon-p.Lon),
+			Time: t,
+		}
+
+		if p.Elevation.IsSome() && q.Elevation.IsSome() {
+			located.Elevation = option.Some(p.Elevation.Get() + f*(q.Elevation.Get()-p.Elevation.Get()))
 		}
+
+		return located, delta, true
+
+	case withinTolerance(before):
+		return before.Get(), delta, true
+
+	case withinTolerance(after):
+		return after.Get(), delta, true
 	}
 
-	return points[iBest], durBest
+	return geotrack.GPXPoint{}, delta, false
 }
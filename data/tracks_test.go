@@ -0,0 +1,102 @@
+package data
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/bgraf/rueckblick/geotrack"
+	"github.com/bgraf/rueckblick/option"
+)
+
+func TestLocateTime(t *testing.T) {
+	start := time.Date(2024, 7, 1, 10, 0, 0, 0, time.UTC)
+
+	points := []geotrack.GPXPoint{
+		{Lat: 10, Lon: 20, Time: start},
+		{Lat: 11, Lon: 22, Time: start.Add(time.Minute)},
+		// Gap in the recording
+		{Lat: 20, Lon: 30, Time: start.Add(time.Hour)},
+	}
+
+	tests := []struct {
+		name   string
+		offset time.Duration
+		want   option.Option[[2]float64]
+	}{
+		{"exact point", time.Minute, option.Some([2]float64{11, 22})},
+		{"interpolated", 15 * time.Second, option.Some([2]float64{10.25, 20.5})},
+		{"before track", -90 * time.Second, option.Some([2]float64{10, 20})},
+		{"too early", -3 * time.Minute, option.None[[2]float64]()},
+		{"after point before gap", 2 * time.Minute, option.Some([2]float64{11, 22})},
+		{"within gap", 30 * time.Minute, option.None[[2]float64]()},
+		{"before point after gap", 59 * time.Minute, option.Some([2]float64{20, 30})},
+		{"too late", 63 * time.Minute, option.None[[2]float64]()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _, ok := locateTime(points, start.Add(tt.offset), 2*time.Minute)
+			if ok != tt.want.IsSome() {
+				t.Fatalf("got located %v, want %v", ok, tt.want.IsSome())
+			}
+
+			if ok {
+				want := tt.want.Get()
+				if math.Abs(p.Lat-want[0]) > 1e-9 || math.Abs(p.Lon-want[1]) > 1e-9 {
+					t.Errorf("got %v, %v, want %v, %v", p.Lat, p.Lon, want[0], want[1])
+				}
+			}
+		})
+	}
+}
+
+func TestCameraClockPhotoTime(t *testing.T) {
+	wallClock := time.Date(2024, 7, 1, 18, 0, 0, 0, time.Local)
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorded := time.Date(2024, 7, 1, 18, 0, 0, 0, time.FixedZone("+02:00", 2*60*60))
+
+	tests := []struct {
+		name  string
+		clock CameraClock
+		img   Image
+		want  time.Time
+	}{
+		{
+			name: "local time",
+			img:  Image{Timestamp: option.Some(wallClock)},
+			want: wallClock,
+		},
+		{
+			name:  "time zone of the document",
+			clock: CameraClock{Location: tokyo},
+			img:   Image{Timestamp: option.Some(wallClock)},
+			want:  time.Date(2024, 7, 1, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "recorded offset takes precedence",
+			clock: CameraClock{Location: tokyo},
+			img:   Image{Timestamp: option.Some(recorded), TimeOffset: option.Some(2 * time.Hour)},
+			want:  time.Date(2024, 7, 1, 16, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "clock offset",
+			clock: CameraClock{Location: tokyo, Offset: -90 * time.Second},
+			img:   Image{Timestamp: option.Some(wallClock)},
+			want:  time.Date(2024, 7, 1, 8, 58, 30, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.clock.PhotoTime(tt.img)
+			if !ok || !got.Equal(tt.want) {
+				t.Errorf("got %s (%v), want %s", got, ok, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,13 @@
+package filesystem
+
+import "os"
+
+// LinkOrCopy creates a hard link of src at dst. If linking is not possible, e.g.,
+// because both paths reside on different file systems, the file is copied instead.
+func LinkOrCopy(src, dst string) error {
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+
+	return Copy(src, dst)
+}
@@ -0,0 +1,3 @@
+// Package geocode finds the names of places near geographic coordinates in local extracts
+// of GeoNames or OpenStreetMap, such that no network access is needed.
+package geocode
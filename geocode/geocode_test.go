@@ -0,0 +1,89 @@
+package geocode
+
+import (
+	"testing"
+
+	"github.com/bgraf/rueckblick/geotrack"
+)
+
+func TestLoadPlaces(t *testing.T) {
+	tests := []struct {
+		path  string
+		names []string
+	}{
+		// Administrative divisions are skipped.
+		{"testdata/places.txt", []string{"Osnabrück", "Wallenhorst", "Piesberg", "Belm"}},
+		// Unnamed places and other geometries are skipped.
+		{"testdata/places.geojson", []string{"Osnabrück", "Schinkel"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			places, err := LoadPlaces(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(places) != len(tt.names) {
+				t.Fatalf("got %d places %v, want %v", len(places), places, tt.names)
+			}
+
+			for i, name := range tt.names {
+				if places[i].Name != name {
+					t.Errorf("place %d is '%s', want '%s'", i, places[i].Name, name)
+				}
+			}
+
+			if places[0].Lat != 52.27264 || places[0].Lon != 8.0498 || places[0].Population != 164748 {
+				t.Errorf("unexpected place %+v", places[0])
+			}
+		})
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	places, err := LoadPlaces("testdata/places.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ix := NewIndex(places)
+
+	if _, _, ok := ix.Nearest(geotrack.GPXPoint{Lat: 50, Lon: 8}, 5000); ok {
+		t.Errorf("found a place outside of the radius")
+	}
+
+	// From the center of Osnabrück north towards Wallenhorst, the points of the
+	// beginning are duplicated and must not count.
+	points := []geotrack.GPXPoint{
+		{Lat: 52.2727, Lon: 8.0499},
+		{Lat: 52.2727, Lon: 8.0499},
+		{Lat: 52.2727, Lon: 8.0499},
+		{Lat: 52.2800, Lon: 8.0450},
+		{Lat: 52.2900, Lon: 8.0400},
+		{Lat: 52.3200, Lon: 8.0050},
+		{Lat: 52.3490, Lon: 8.0170},
+	}
+
+	suggestions := ix.Suggest(points, 5000)
+
+	want := []Suggestion{
+		{Name: "Osnabrück", Samples: 3},
+		{Name: "Piesberg", Samples: 1},
+		{Name: "Wallenhorst", Samples: 1},
+	}
+
+	if len(suggestions) != len(want) {
+		t.Fatalf("got %v, want %v", suggestions, want)
+	}
+
+	for i, s := range want {
+		if suggestions[i].Name != s.Name || suggestions[i].Samples != s.Samples {
+			t.Errorf("suggestion %d is %+v, want %+v", i, suggestions[i], s)
+		}
+	}
+
+	if suggestions[0].Distance > 10 {
+		t.Errorf("distance to Osnabrück %f, want less than 10m", suggestions[0].Distance)
+	}
+}
@@ -0,0 +1,126 @@
+package geocode
+
+import (
+	"math"
+	"sort"
+
+	"github.com/bgraf/rueckblick/geotrack"
+)
+
+// Edge length in degrees of the grid cells places are sorted into.
+const cellSize = 0.1
+
+// Minimal distance in meters between the track points considered by Suggest, such that
+// long stays at one place do not outweigh the rest of a track.
+const sampleSpacing = 250.0
+
+// Index finds the places nearest to coordinates.
+type Index struct {
+	places []Place
+	cells  map[cell][]int
+}
+
+type cell struct{ lat, lon int }
+
+func cellOf(lat, lon float64) cell {
+	return cell{int(math.Floor(lat / cellSize)), int(math.Floor(lon / cellSize))}
+}
+
+// NewIndex builds an index of the places.
+func NewIndex(places []Place) *Index {
+	ix := &Index{
+		places: places,
+		cells:  make(map[cell][]int),
+	}
+
+	for i, p := range places {
+		c := cellOf(p.Lat, p.Lon)
+		ix.cells[c] = append(ix.cells[c], i)
+	}
+
+	return ix
+}
+
+// Len returns the number of places in the index.
+func (ix *Index) Len() int {
+	return len(ix.places)
+}
+
+// Nearest returns the place nearest to the point within the radius in meters and its
+// distance.
+func (ix *Index) Nearest(p geotrack.GPXPoint, radius float64) (Place, float64, bool) {
+	// Degrees of latitude and longitude covered by the radius.
+	dLat := radius / 111_000
+	dLon := dLat / max(math.Cos(p.Lat*math.Pi/180), 0.01)
+
+	c0 := cellOf(p.Lat-dLat, p.Lon-dLon)
+	c1 := cellOf(p.Lat+dLat, p.Lon+dLon)
+
+	best, bestDist := -1, radius
+
+	for lat := c0.lat; lat <= c1.lat; lat++ {
+		for lon := c0.lon; lon <= c1.lon; lon++ {
+			for _, i := range ix.cells[cell{lat, lon}] {
+				q := geotrack.GPXPoint{Lat: ix.places[i].Lat, Lon: ix.places[i].Lon}
+				if d := geotrack.Distance(p, q); d <= bestDist {
+					best, bestDist = i, d
+				}
+			}
+		}
+	}
+
+	if best < 0 {
+		return Place{}, 0, false
+	}
+
+	return ix.places[best], bestDist, true
+}
+
+// Suggestion is the name of a place near some of the given points.
+type Suggestion struct {
+	Name     string
+	Samples  int     // Number of points the place is nearest to
+	Distance float64 // Distance in meters of the closest point
+}
+
+// Suggest returns the names of the places nearest to the points within the radius in
+// meters, ordered by the number of points they are nearest to. Points closer than
+// sampleSpacing to the previously considered point are skipped.
+func (ix *Index) Suggest(points []geotrack.GPXPoint, radius float64) []Suggestion {
+	byName := make(map[string]*Suggestion)
+	var suggestions []*Suggestion
+
+	var last geotrack.GPXPoint
+	for i, p := range points {
+		if i > 0 && geotrack.Distance(last, p) < sampleSpacing {
+			continue
+		}
+		last = p
+
+		place, d, ok := ix.Nearest(p, radius)
+		if !ok {
+			continue
+		}
+
+		s, ok := byName[place.Name]
+		if !ok {
+			s = &Suggestion{Name: place.Name, Distance: d}
+			byName[place.Name] = s
+			suggestions = append(suggestions, s)
+		}
+
+		s.Samples++
+		s.Distance = min(s.Distance, d)
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].Samples > suggestions[j].Samples
+	})
+
+	result := make([]Suggestion, len(suggestions))
+	for i, s := range suggestions {
+		result[i] = *s
+	}
+
+	return result
+}
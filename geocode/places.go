@@ -0,0 +1,179 @@
+package geocode
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Place is a named location.
+type Place struct {
+	Name       string
+	Lat, Lon   float64
+	Kind       string // GeoNames feature code or OSM place type, e.g., PPL or village
+	Population int
+}
+
+// LoadPlaces reads the places of an extract. Supported are GeoNames dumps, optionally
+// zipped, with the extension .txt, .tsv or .zip, and GeoJSON files with the extension
+// .geojson or .json holding OSM place nodes.
+func LoadPlaces(path string) ([]Place, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".txt", ".tsv":
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+
+		defer func() { _ = f.Close() }()
+
+		return ReadGeoNames(f)
+
+	case ".zip":
+		return loadGeoNamesZip(path)
+
+	case ".geojson", ".json":
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+
+		defer func() { _ = f.Close() }()
+
+		return ReadOSMPlaces(f)
+	}
+
+	return nil, fmt.Errorf("unsupported place extract '%s'", path)
+}
+
+// Feature classes of GeoNames read by ReadGeoNames: populated places, parks and areas, and
+// mountains, hills and other terrain.
+var geoNamesClasses = map[string]bool{"P": true, "L": true, "T": true}
+
+// ReadGeoNames reads a GeoNames dump of tab separated lines, like cities500.txt or the
+// dump of a country.
+func ReadGeoNames(r io.Reader) ([]Place, error) {
+	var places []Place
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) < 15 || !geoNamesClasses[fields[6]] {
+			continue
+		}
+
+		lat, errLat := strconv.ParseFloat(fields[4], 64)
+		lon, errLon := strconv.ParseFloat(fields[5], 64)
+		if err := errors.Join(errLat, errLon); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		population, _ := strconv.Atoi(fields[14])
+
+		places = append(places, Place{
+			Name:       fields[1],
+			Lat:        lat,
+			Lon:        lon,
+			Kind:       fields[7],
+			Population: population,
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return places, nil
+}
+
+// loadGeoNamesZip reads the dump within a zip archive as distributed by GeoNames.
+func loadGeoNamesZip(path string) ([]Place, error) {
+	z, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = z.Close() }()
+
+	for _, f := range z.File {
+		if strings.ToLower(filepath.Ext(f.Name)) != ".txt" || strings.EqualFold(f.Name, "readme.txt") {
+			continue
+		}
+
+		r, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+
+		places, err := ReadGeoNames(r)
+		_ = r.Close()
+
+		return places, err
+	}
+
+	return nil, errors.New("no GeoNames dump in archive")
+}
+
+// ReadOSMPlaces reads the point features of a GeoJSON file with `name` and `place`
+// properties, as exported from OSM place nodes, e.g., by osmium.
+func ReadOSMPlaces(r io.Reader) ([]Place, error) {
+	var collection struct {
+		Features []struct {
+			Geometry struct {
+				Type        string          `json:"type"`
+				Coordinates json.RawMessage `json:"coordinates"`
+			} `json:"geometry"`
+			Properties map[string]any `json:"properties"`
+		} `json:"features"`
+	}
+
+	if err := json.NewDecoder(r).Decode(&collection); err != nil {
+		return nil, err
+	}
+
+	var places []Place
+
+	for _, feature := range collection.Features {
+		name, _ := feature.Properties["name"].(string)
+		if name == "" || feature.Geometry.Type != "Point" {
+			continue
+		}
+
+		var coords []float64
+		if err := json.Unmarshal(feature.Geometry.Coordinates, &coords); err != nil || len(coords) < 2 {
+			return nil, fmt.Errorf("invalid coordinates of place '%s'", name)
+		}
+
+		kind, _ := feature.Properties["place"].(string)
+
+		var population int
+		switch v := feature.Properties["population"].(type) {
+		case string:
+			population, _ = strconv.Atoi(v)
+		case float64:
+			population = int(v)
+		}
+
+		places = append(places, Place{
+			Name:       name,
+			Lat:        coords[1],
+			Lon:        coords[0],
+			Kind:       kind,
+			Population: population,
+		})
+	}
+
+	return places, nil
+}
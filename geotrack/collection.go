@@ -0,0 +1,111 @@
+package geotrack
+
+// Collection is the structured content of a track file.
+type Collection struct {
+	Tracks    []Track
+	Routes    []Route
+	Waypoints []Waypoint
+
+	// Number of unreadable entries skipped while loading, e.g., lines with bad checksums.
+	Skipped int
+}
+
+// Track is a recorded path, e.g., of one day of a multi-day hike. Gaps in the recording
+// separate its segments.
+type Track struct {
+	Name     string
+	Segments []Segment
+}
+
+type Segment struct {
+	Points []GPXPoint
+}
+
+// Route is a planned path.
+type Route struct {
+	Name   string
+	Points []GPXPoint
+}
+
+// Waypoint is a named point of interest.
+type Waypoint struct {
+	Point       GPXPoint
+	Name        string
+	Description string
+}
+
+// NewSinglePathCollection returns a collection of one track with one segment.
+func NewSinglePathCollection(points []GPXPoint) *Collection {
+	return &Collection{
+		Tracks: []Track{{Segments: []Segment{{Points: points}}}},
+	}
+}
+
+// Points returns the points of all track segments.
+func (c *Collection) Points() []GPXPoint {
+	var points []GPXPoint
+
+	for _, segment := range c.Segments() {
+		points = append(points, segment.Points...)
+	}
+
+	return points
+}
+
+// Segments returns the segments of all tracks.
+func (c *Collection) Segments() []Segment {
+	var segments []Segment
+
+	for _, track := range c.Tracks {
+		segments = append(segments, track.Segments...)
+	}
+
+	return segments
+}
+
+// Statistics combines the statistics of all track segments, such that gaps between
+// segments count neither as distance nor as duration.
+func (c *Collection) Statistics() Statistics {
+	var s Statistics
+
+	for _, segment := range c.Segments() {
+		t := ComputeStatistics(segment.Points)
+
+		s.Distance += t.Distance
+		s.Duration += t.Duration
+		s.MovingTime += t.MovingTime
+		s.MaxSpeed = max(s.MaxSpeed, t.MaxSpeed)
+
+		if !t.HasElevation {
+			continue
+		}
+
+		if !s.HasElevation {
+			s.HasElevation = true
+			s.MinElevation, s.MaxElevation = t.MinElevation, t.MaxElevation
+		}
+
+		s.Ascent += t.Ascent
+		s.Descent += t.Descent
+		s.MinElevation = min(s.MinElevation, t.MinElevation)
+		s.MaxElevation = max(s.MaxElevation, t.MaxElevation)
+	}
+
+	return s
+}
+
+// ElevationProfile concatenates the elevation profiles of all track segments.
+func (c *Collection) ElevationProfile() []ProfilePoint {
+	var profile []ProfilePoint
+
+	offset := 0.0
+	for _, segment := range c.Segments() {
+		for _, p := range ElevationProfile(segment.Points) {
+			profile = append(profile, ProfilePoint{Distance: offset + p.Distance, Elevation: p.Elevation})
+		}
+
+		offset += ComputeStatistics(segment.Points).Distance
+	}
+
+	return profile
+}
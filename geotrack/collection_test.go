@@ -0,0 +1,39 @@
+package geotrack
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestLoadGPX(t *testing.T) {
+	c, err := LoadGPX("testdata/multiday.gpx")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(c.Tracks) != 2 || c.Tracks[0].Name != "Day 1" || c.Tracks[1].Name != "Day 2" {
+		t.Fatalf("unexpected tracks %+v", c.Tracks)
+	}
+
+	if len(c.Tracks[0].Segments) != 2 || len(c.Segments()) != 3 || len(c.Points()) != 6 {
+		t.Errorf("unexpected segments %+v", c.Tracks)
+	}
+
+	if len(c.Routes) != 1 || c.Routes[0].Name != "Planned" || len(c.Routes[0].Points) != 2 {
+		t.Errorf("unexpected routes %+v", c.Routes)
+	}
+
+	if len(c.Waypoints) != 2 || c.Waypoints[0].Name != "Hut" || c.Waypoints[0].Description != "Overnight stay" {
+		t.Errorf("unexpected waypoints %+v", c.Waypoints)
+	}
+
+	// Gaps between segments count neither as distance nor as duration.
+	s := c.Statistics()
+	if math.Abs(s.Distance-3*111.2) > 1 {
+		t.Errorf("distance %f, want about 333.6", s.Distance)
+	}
+	if s.Duration != 3*time.Minute {
+		t.Errorf("duration %s, want 3m", s.Duration)
+	}
+}
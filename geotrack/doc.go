@@ -1,2 +1,3 @@
-// Package geotrack reads tracks of geolocations in GPX and NMEA formats.
+// Package geotrack reads tracks of geolocations in GPX, NMEA, FIT, TCX, KML, KMZ and
+// GeoJSON formats.
 package geotrack
@@ -0,0 +1,280 @@
+package geotrack
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"github.com/bgraf/rueckblick/option"
+)
+
+// Global message numbers and field numbers of the FIT profile used to read tracks.
+const (
+	fitMessageRecord = 20
+	fitMessageEvent  = 21
+
+	fitFieldTimestamp        = 253
+	fitFieldPositionLat      = 0
+	fitFieldPositionLong     = 1
+	fitFieldAltitude         = 2
+	fitFieldEnhancedAltitude = 78
+
+	fitFieldEvent     = 0
+	fitFieldEventType = 1
+
+	fitEventTimer       = 0
+	fitEventTypeStop    = 1
+	fitEventTypeStopAll = 4
+)
+
+// Start of the FIT time scale.
+var fitEpoch = time.Date(1989, 12, 31, 0, 0, 0, 0, time.UTC)
+
+var errFITTruncated = errors.New("truncated FIT file")
+
+type fitField struct {
+	number byte
+	size   int
+}
+
+type fitDefinition struct {
+	global    uint16
+	byteOrder binary.ByteOrder
+	fields    []fitField
+	size      int // Total size of a data message in bytes
+}
+
+// fitReader decodes the record messages of a FIT activity or course into a track.
+type fitReader struct {
+	data        []byte
+	definitions [16]*fitDefinition
+	timestamp   uint32 // Most recent timestamp, the reference of compressed timestamps
+
+	segments []Segment
+	points   []GPXPoint
+}
+
+// LoadFIT reads the recorded positions of a FIT file as written by bike computers and
+// watches. Stopping the timer ends a segment.
+func LoadFIT(trackFilePath string) (*Collection, error) {
+	data, err := os.ReadFile(trackFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &fitReader{data: data}
+
+	// A file may consist of several chained FIT files.
+	for len(r.data) > 0 {
+		if err := r.readFile(); err != nil {
+			return nil, err
+		}
+	}
+
+	r.endSegment()
+
+	return &Collection{Tracks: []Track{{Segments: r.segments}}}, nil
+}
+
+func (r *fitReader) readFile() error {
+	if len(r.data) < 12 {
+		return errFITTruncated
+	}
+
+	headerSize := int(r.data[0])
+	if headerSize < 12 || len(r.data) < headerSize || string(r.data[8:12]) != ".FIT" {
+		return errors.New("not a FIT file")
+	}
+
+	dataSize := int(binary.LittleEndian.Uint32(r.data[4:8]))
+	end := headerSize + dataSize
+	if len(r.data) < end {
+		return errFITTruncated
+	}
+
+	records := r.data[headerSize:end]
+	for len(records) > 0 {
+		n, err := r.readRecord(records)
+		if err != nil {
+			return err
+		}
+
+		records = records[n:]
+	}
+
+	// Skip the trailing CRC.
+	r.data = r.data[min(end+2, len(r.data)):]
+	r.definitions = [16]*fitDefinition{}
+
+	return nil
+}
+
+// readRecord decodes the record at the start of b and returns its size.
+func (r *fitReader) readRecord(b []byte) (int, error) {
+	header := b[0]
+
+	if header&0x80 != 0 {
+		// Data message with compressed timestamp
+		local := (header >> 5) & 0x03
+		offset := uint32(header & 0x1f)
+
+		r.timestamp += (offset - r.timestamp&0x1f) & 0x1f
+
+		return r.readData(b[1:], local, true)
+	}
+
+	local := header & 0x0f
+
+	if header&0x40 == 0 {
+		return r.readData(b[1:], local, false)
+	}
+
+	// Definition message
+	if len(b) < 6 {
+		return 0, errFITTruncated
+	}
+
+	def := &fitDefinition{byteOrder: binary.LittleEndian}
+	if b[2] == 1 {
+		def.byteOrder = binary.BigEndian
+	}
+
+	def.global = def.byteOrder.Uint16(b[3:5])
+	numFields := int(b[5])
+
+	n := 6 + 3*numFields
+	if len(b) < n {
+		return 0, errFITTruncated
+	}
+
+	for i := 0; i < numFields; i++ {
+		field := fitField{number: b[6+3*i], size: int(b[7+3*i])}
+		def.fields = append(def.fields, field)
+		def.size += field.size
+	}
+
+	// Developer fields are skipped, only their sizes matter.
+	if header&0x20 != 0 {
+		if len(b) < n+1 {
+			return 0, errFITTruncated
+		}
+
+		numDevFields := int(b[n])
+		n++
+
+		if len(b) < n+3*numDevFields {
+			return 0, errFITTruncated
+		}
+
+		for i := 0; i < numDevFields; i++ {
+			def.size += int(b[n+3*i+1])
+		}
+
+		n += 3 * numDevFields
+	}
+
+	r.definitions[local] = def
+
+	return n, nil
+}
+
+func (r *fitReader) readData(b []byte, local byte, hasTimestamp bool) (int, error) {
+	def := r.definitions[local]
+	if def == nil {
+		return 0, fmt.Errorf("data message of undefined local type %d", local)
+	}
+
+	if len(b) < def.size {
+		return 0, errFITTruncated
+	}
+
+	values := make(map[byte][]byte, len(def.fields))
+
+	offset := 0
+	for _, field := range def.fields {
+		values[field.number] = b[offset : offset+field.size]
+		offset += field.size
+	}
+
+	if v, ok := values[fitFieldTimestamp]; ok && len(v) == 4 {
+		if t := def.byteOrder.Uint32(v); t != math.MaxUint32 {
+			r.timestamp = t
+			hasTimestamp = true
+		}
+	}
+
+	switch def.global {
+	case fitMessageRecord:
+		r.readPoint(def, values, hasTimestamp)
+
+	case fitMessageEvent:
+		event, eventType := values[fitFieldEvent], values[fitFieldEventType]
+		if len(event) != 1 || len(eventType) != 1 || event[0] != fitEventTimer {
+			break
+		}
+
+		if eventType[0] == fitEventTypeStop || eventType[0] == fitEventTypeStopAll {
+			r.endSegment()
+		}
+	}
+
+	return 1 + def.size, nil
+}
+
+func (r *fitReader) readPoint(def *fitDefinition, values map[byte][]byte, hasTimestamp bool) {
+	lat, okLat := fitSemicircles(def, values[fitFieldPositionLat])
+	lon, okLon := fitSemicircles(def, values[fitFieldPositionLong])
+	if !okLat || !okLon {
+		// Records without position, e.g., of indoor activities or while searching
+		// satellites.
+		return
+	}
+
+	p := GPXPoint{Lat: lat, Lon: lon}
+
+	if hasTimestamp {
+		p.Time = fitEpoch.Add(time.Duration(r.timestamp) * time.Second)
+	}
+
+	if v := values[fitFieldEnhancedAltitude]; len(v) == 4 {
+		if a := def.byteOrder.Uint32(v); a != math.MaxUint32 {
+			p.Elevation = option.Some(float64(a)/5 - 500)
+		}
+	}
+
+	if v := values[fitFieldAltitude]; p.Elevation.IsNone() && len(v) == 2 {
+		if a := def.byteOrder.Uint16(v); a != math.MaxUint16 {
+			p.Elevation = option.Some(float64(a)/5 - 500)
+		}
+	}
+
+	r.points = append(r.points, p)
+}
+
+func (r *fitReader) endSegment() {
+	if len(r.points) > 0 {
+		r.segments = append(r.segments, Segment{Points: r.points})
+		r.points = nil
+	}
+}
+
+// fitSemicircles converts a coordinate in semicircles to degrees.
+func fitSemicircles(def *fitDefinition, v []byte) (float64, bool) {
+	if len(v) != 4 {
+		return 0, false
+	}
+
+	s := int32(def.byteOrder.Uint32(v))
+	if s == math.MaxInt32 {
+		return 0, false
+	}
+
+	return float64(s) * 180 / (1 << 31), true
+}
+
+func sniffFIT(head []byte) bool {
+	return len(head) >= 12 && string(head[8:12]) == ".FIT"
+}
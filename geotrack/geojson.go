@@ -0,0 +1,195 @@
+package geotrack
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/bgraf/rueckblick/option"
+)
+
+type geoJSONObject struct {
+	Type        string          `json:"type"`
+	Features    []geoJSONObject `json:"features"`
+	Geometry    *geoJSONObject  `json:"geometry"`
+	Geometries  []geoJSONObject `json:"geometries"`
+	Coordinates json.RawMessage `json:"coordinates"`
+	Properties  struct {
+		Name        string          `json:"name"`
+		Description string          `json:"description"`
+		CoordTimes  json.RawMessage `json:"coordTimes"`
+	} `json:"properties"`
+}
+
+// LoadGeoJSON reads the features of a GeoJSON file. LineStrings become tracks, and points
+// become waypoints. Timestamps of positions are read from the `coordTimes` property as
+// written by common GPX converters.
+func LoadGeoJSON(trackFilePath string) (*Collection, error) {
+	data, err := os.ReadFile(trackFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var root geoJSONObject
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+
+	c := &Collection{}
+	if err := c.addGeoJSONObject(root, root); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// addGeoJSONObject adds an object to the collection, taking name and timestamps from the
+// properties of its feature.
+func (c *Collection) addGeoJSONObject(obj geoJSONObject, feature geoJSONObject) error {
+	switch obj.Type {
+	case "FeatureCollection":
+		for _, f := range obj.Features {
+			if err := c.addGeoJSONObject(f, f); err != nil {
+				return err
+			}
+		}
+
+	case "Feature":
+		if obj.Geometry != nil {
+			return c.addGeoJSONObject(*obj.Geometry, feature)
+		}
+
+	case "GeometryCollection":
+		for _, g := range obj.Geometries {
+			if err := c.addGeoJSONObject(g, feature); err != nil {
+				return err
+			}
+		}
+
+	case "Point", "MultiPoint":
+		var coords [][]float64
+		if err := unmarshalGeoJSONCoordinates(obj, &coords); err != nil {
+			return err
+		}
+
+		for _, coord := range coords {
+			c.Waypoints = append(c.Waypoints, Waypoint{
+				Point:       geoJSONPoint(coord),
+				Name:        feature.Properties.Name,
+				Description: feature.Properties.Description,
+			})
+		}
+
+	case "LineString", "MultiLineString":
+		var lines [][][]float64
+		if err := unmarshalGeoJSONCoordinates(obj, &lines); err != nil {
+			return err
+		}
+
+		times := geoJSONTimes(feature, obj.Type == "MultiLineString")
+
+		t := Track{Name: feature.Properties.Name}
+		for i, line := range lines {
+			points := make([]GPXPoint, len(line))
+			for j, coord := range line {
+				points[j] = geoJSONPoint(coord)
+
+				if i < len(times) && j < len(times[i]) {
+					points[j].Time = times[i][j]
+				}
+			}
+
+			if len(points) > 0 {
+				t.Segments = append(t.Segments, Segment{Points: points})
+			}
+		}
+
+		c.Tracks = append(c.Tracks, t)
+	}
+
+	return nil
+}
+
+// unmarshalGeoJSONCoordinates unmarshals the coordinates of a geometry into a slice of
+// lines or points, wrapping single lines and points.
+func unmarshalGeoJSONCoordinates[T any](obj geoJSONObject, v *[]T) error {
+	var err error
+
+	switch obj.Type {
+	case "Point", "LineString":
+		var single T
+		err = json.Unmarshal(obj.Coordinates, &single)
+		*v = []T{single}
+
+	default:
+		err = json.Unmarshal(obj.Coordinates, v)
+	}
+
+	if err != nil {
+		return fmt.Errorf("invalid coordinates of %s: %w", obj.Type, err)
+	}
+
+	for _, coord := range geoJSONPositions(*v) {
+		if len(coord) < 2 {
+			return fmt.Errorf("invalid coordinates of %s", obj.Type)
+		}
+	}
+
+	return nil
+}
+
+func geoJSONPositions(v any) [][]float64 {
+	switch v := v.(type) {
+	case [][]float64:
+		return v
+
+	case [][][]float64:
+		var positions [][]float64
+		for _, line := range v {
+			positions = append(positions, line...)
+		}
+		return positions
+	}
+
+	return nil
+}
+
+// geoJSONTimes returns the timestamps of the lines of a feature. Unparsable timestamps
+// are ignored.
+func geoJSONTimes(feature geoJSONObject, multi bool) [][]time.Time {
+	if len(feature.Properties.CoordTimes) == 0 {
+		return nil
+	}
+
+	var raw [][]string
+	if multi {
+		_ = json.Unmarshal(feature.Properties.CoordTimes, &raw)
+	} else {
+		var line []string
+		_ = json.Unmarshal(feature.Properties.CoordTimes, &line)
+		raw = [][]string{line}
+	}
+
+	times := make([][]time.Time, len(raw))
+	for i, line := range raw {
+		times[i] = make([]time.Time, len(line))
+		for j, s := range line {
+			if t, err := time.Parse(time.RFC3339, s); err == nil {
+				times[i][j] = t.UTC()
+			}
+		}
+	}
+
+	return times
+}
+
+// geoJSONPoint converts a position of the form `[lon, lat, alt]`.
+func geoJSONPoint(coord []float64) GPXPoint {
+	p := GPXPoint{Lon: coord[0], Lat: coord[1]}
+	if len(coord) > 2 {
+		p.Elevation = option.Some(coord[2])
+	}
+
+	return p
+}
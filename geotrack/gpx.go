@@ -3,22 +3,75 @@ package geotrack
 import (
 	"fmt"
 
+	"github.com/bgraf/rueckblick/option"
 	"github.com/tkrajina/gpxgo/gpx"
 )
 
-func LoadGPXTrack(trackFilePath string) (points []GPXPoint, err error) {
+// LoadGPX reads the tracks, routes and waypoints of a GPX file.
+func LoadGPX(trackFilePath string) (*Collection, error) {
 	gpxData, err := gpx.ParseFile(trackFilePath)
 	if err != nil {
 		return nil, fmt.Errorf("read GPX file: %w", err)
 	}
 
+	c := &Collection{}
+
 	for _, track := range gpxData.Tracks {
+		t := Track{Name: track.Name}
+
 		for _, segment := range track.Segments {
-			for _, p := range segment.Points {
-				points = append(points, GPXPoint{Lat: p.Latitude, Lon: p.Longitude, Time: p.Timestamp})
+			if len(segment.Points) == 0 {
+				continue
 			}
+
+			t.Segments = append(t.Segments, Segment{Points: convertGPXPoints(segment.Points)})
 		}
+
+		c.Tracks = append(c.Tracks, t)
+	}
+
+	for _, route := range gpxData.Routes {
+		c.Routes = append(c.Routes, Route{Name: route.Name, Points: convertGPXPoints(route.Points)})
+	}
+
+	for _, p := range gpxData.Waypoints {
+		c.Waypoints = append(c.Waypoints, Waypoint{
+			Point:       convertGPXPoint(p),
+			Name:        p.Name,
+			Description: p.Description,
+		})
+	}
+
+	return c, nil
+}
+
+// LoadGPXTrack reads the points of all tracks and segments of a GPX file.
+func LoadGPXTrack(trackFilePath string) (points []GPXPoint, err error) {
+	c, err := LoadGPX(trackFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Points(), nil
+}
+
+func convertGPXPoints(ps []gpx.GPXPoint) []GPXPoint {
+	points := make([]GPXPoint, len(ps))
+	for i, p := range ps {
+		points[i] = convertGPXPoint(p)
+	}
+
+	return points
+}
+
+func convertGPXPoint(p gpx.GPXPoint) GPXPoint {
+	point := GPXPoint{Lat: p.Latitude, Lon: p.Longitude, Time: p.Timestamp, Fix: p.TypeOfGpsFix}
+	if p.Elevation.NotNull() {
+		point.Elevation = option.Some(p.Elevation.Value())
+	}
+	if p.Satellites.NotNull() {
+		point.Satellites = option.Some(p.Satellites.Value())
 	}
 
-	return
+	return point
 }
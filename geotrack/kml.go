@@ -0,0 +1,275 @@
+package geotrack
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bgraf/rueckblick/option"
+)
+
+type kmlPlacemark struct {
+	Name          string            `xml:"name"`
+	Description   string            `xml:"description"`
+	Point         *kmlPoint         `xml:"Point"`
+	LineString    *kmlLineString    `xml:"LineString"`
+	Track         *kmlTrack         `xml:"Track"`
+	MultiTrack    *kmlMultiTrack    `xml:"MultiTrack"`
+	MultiGeometry *kmlMultiGeometry `xml:"MultiGeometry"`
+}
+
+type kmlPoint struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+type kmlLineString struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+// kmlTrack is a gx:Track, whose positions carry timestamps.
+type kmlTrack struct {
+	When  []string `xml:"when"`
+	Coord []string `xml:"coord"`
+}
+
+type kmlMultiTrack struct {
+	Tracks []kmlTrack `xml:"Track"`
+}
+
+type kmlMultiGeometry struct {
+	Points      []kmlPoint         `xml:"Point"`
+	LineStrings []kmlLineString    `xml:"LineString"`
+	Geometries  []kmlMultiGeometry `xml:"MultiGeometry"`
+}
+
+// LoadKML reads the placemarks of a KML file. Paths become tracks, and points become
+// waypoints.
+func LoadKML(trackFilePath string) (*Collection, error) {
+	f, err := os.Open(trackFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = f.Close() }()
+
+	return readKML(f)
+}
+
+// LoadKMZ reads the main KML document of a zipped KML file.
+func LoadKMZ(trackFilePath string) (*Collection, error) {
+	z, err := zip.OpenReader(trackFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = z.Close() }()
+
+	// The main document is called doc.kml by convention, otherwise it is the first
+	// KML file in the root directory.
+	var doc *zip.File
+	for _, f := range z.File {
+		if path.Dir(f.Name) != "." || strings.ToLower(path.Ext(f.Name)) != ".kml" {
+			continue
+		}
+
+		if doc == nil || f.Name == "doc.kml" {
+			doc = f
+		}
+	}
+
+	if doc == nil {
+		return nil, errors.New("no KML document in archive")
+	}
+
+	r, err := doc.Open()
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = r.Close() }()
+
+	return readKML(r)
+}
+
+func readKML(r io.Reader) (*Collection, error) {
+	c := &Collection{}
+	decoder := xml.NewDecoder(r)
+
+	// Placemarks may be nested in arbitrary documents and folders.
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "Placemark" {
+			continue
+		}
+
+		var placemark kmlPlacemark
+		if err := decoder.DecodeElement(&placemark, &start); err != nil {
+			return nil, err
+		}
+
+		if err := c.addKMLPlacemark(placemark); err != nil {
+			return nil, fmt.Errorf("placemark '%s': %w", placemark.Name, err)
+		}
+	}
+
+	return c, nil
+}
+
+func (c *Collection) addKMLPlacemark(placemark kmlPlacemark) error {
+	var (
+		points   []kmlPoint
+		lines    []kmlLineString
+		segments []Segment
+	)
+
+	if placemark.Point != nil {
+		points = append(points, *placemark.Point)
+	}
+
+	if placemark.LineString != nil {
+		lines = append(lines, *placemark.LineString)
+	}
+
+	var gather func(g kmlMultiGeometry)
+	gather = func(g kmlMultiGeometry) {
+		points = append(points, g.Points...)
+		lines = append(lines, g.LineStrings...)
+		for _, h := range g.Geometries {
+			gather(h)
+		}
+	}
+
+	if placemark.MultiGeometry != nil {
+		gather(*placemark.MultiGeometry)
+	}
+
+	var tracks []kmlTrack
+	if placemark.Track != nil {
+		tracks = append(tracks, *placemark.Track)
+	}
+
+	if placemark.MultiTrack != nil {
+		tracks = append(tracks, placemark.MultiTrack.Tracks...)
+	}
+
+	for _, track := range tracks {
+		ps, err := parseKMLTrack(track)
+		if err != nil {
+			return err
+		}
+
+		if len(ps) > 0 {
+			segments = append(segments, Segment{Points: ps})
+		}
+	}
+
+	for _, line := range lines {
+		ps, err := parseKMLCoordinates(line.Coordinates)
+		if err != nil {
+			return err
+		}
+
+		if len(ps) > 0 {
+			segments = append(segments, Segment{Points: ps})
+		}
+	}
+
+	if len(segments) > 0 {
+		c.Tracks = append(c.Tracks, Track{Name: placemark.Name, Segments: segments})
+	}
+
+	for _, point := range points {
+		ps, err := parseKMLCoordinates(point.Coordinates)
+		if err != nil {
+			return err
+		}
+
+		for _, p := range ps {
+			c.Waypoints = append(c.Waypoints, Waypoint{
+				Point:       p,
+				Name:        placemark.Name,
+				Description: strings.TrimSpace(placemark.Description),
+			})
+		}
+	}
+
+	return nil
+}
+
+// parseKMLCoordinates parses whitespace separated tuples of the form `lon,lat[,alt]`.
+func parseKMLCoordinates(s string) ([]GPXPoint, error) {
+	var points []GPXPoint
+
+	for _, tuple := range strings.Fields(s) {
+		p, err := parseKMLTuple(strings.Split(tuple, ","))
+		if err != nil {
+			return nil, err
+		}
+
+		points = append(points, p)
+	}
+
+	return points, nil
+}
+
+func parseKMLTrack(track kmlTrack) ([]GPXPoint, error) {
+	points := make([]GPXPoint, 0, len(track.Coord))
+
+	for i, coord := range track.Coord {
+		p, err := parseKMLTuple(strings.Fields(coord))
+		if err != nil {
+			return nil, err
+		}
+
+		if i < len(track.When) {
+			p.Time, err = time.Parse(time.RFC3339, strings.TrimSpace(track.When[i]))
+			if err != nil {
+				return nil, err
+			}
+			p.Time = p.Time.UTC()
+		}
+
+		points = append(points, p)
+	}
+
+	return points, nil
+}
+
+func parseKMLTuple(fields []string) (GPXPoint, error) {
+	if len(fields) < 2 {
+		return GPXPoint{}, fmt.Errorf("invalid coordinates '%s'", strings.Join(fields, ","))
+	}
+
+	values := make([]float64, len(fields))
+	for i, field := range fields {
+		v, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return GPXPoint{}, fmt.Errorf("invalid coordinates '%s': %w", strings.Join(fields, ","), err)
+		}
+
+		values[i] = v
+	}
+
+	p := GPXPoint{Lon: values[0], Lat: values[1]}
+
+	// Clamped paths have an altitude of zero.
+	if len(values) > 2 && values[2] != 0 {
+		p.Elevation = option.Some(values[2])
+	}
+
+	return p, nil
+}
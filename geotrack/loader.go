@@ -0,0 +1,159 @@
+package geotrack
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Number of leading bytes of a file passed to the Sniff function of loaders.
+const sniffLength = 512
+
+// Loader reads one track file format.
+type Loader struct {
+	Name       string
+	Extensions []string // Lower case file extensions including the dot
+
+	// Sniff reports whether the leading bytes of a file belong to the format.
+	Sniff func(head []byte) bool
+
+	Load func(path string) (*Collection, error)
+}
+
+var (
+	loadersMu sync.RWMutex
+	loaders   = []Loader{
+		{Name: "GPX", Extensions: []string{".gpx"}, Sniff: sniffXML("gpx"), Load: LoadGPX},
+		{Name: "NMEA", Extensions: []string{".nmea", ".txt"}, Sniff: sniffNMEA, Load: LoadNMEA},
+		{Name: "FIT", Extensions: []string{".fit"}, Sniff: sniffFIT, Load: LoadFIT},
+		{Name: "TCX", Extensions: []string{".tcx"}, Sniff: sniffXML("TrainingCenterDatabase"), Load: LoadTCX},
+		{Name: "KML", Extensions: []string{".kml"}, Sniff: sniffXML("kml"), Load: LoadKML},
+		{Name: "KMZ", Extensions: []string{".kmz"}, Sniff: sniffZIP, Load: LoadKMZ},
+		{Name: "GeoJSON", Extensions: []string{".geojson"}, Sniff: sniffGeoJSON, Load: LoadGeoJSON},
+	}
+)
+
+// Register adds a loader for another format. Loaders registered later take precedence
+// over earlier ones for the same extension.
+func Register(l Loader) {
+	loadersMu.Lock()
+	defer loadersMu.Unlock()
+
+	loaders = append([]Loader{l}, loaders...)
+}
+
+// Extensions returns the file extensions of all registered formats.
+func Extensions() []string {
+	loadersMu.RLock()
+	defer loadersMu.RUnlock()
+
+	var extensions []string
+	for _, l := range loaders {
+		for _, ext := range l.Extensions {
+			if !slices.Contains(extensions, ext) {
+				extensions = append(extensions, ext)
+			}
+		}
+	}
+
+	return extensions
+}
+
+// Load reads a track file of any registered format. The format is chosen by the file
+// extension. If several formats share the extension, or the extension is unknown, the
+// content of the file decides.
+func Load(path string) (*Collection, error) {
+	l, err := loaderFor(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := l.Load(path)
+	if err != nil {
+		return nil, fmt.Errorf("read %s file: %w", l.Name, err)
+	}
+
+	return c, nil
+}
+
+func loaderFor(path string) (Loader, error) {
+	loadersMu.RLock()
+	defer loadersMu.RUnlock()
+
+	ext := strings.ToLower(filepath.Ext(path))
+
+	var candidates []Loader
+	for _, l := range loaders {
+		if slices.Contains(l.Extensions, ext) {
+			candidates = append(candidates, l)
+		}
+	}
+
+	if len(candidates) == 1 {
+		return candidates[0], nil
+	}
+
+	if len(candidates) == 0 {
+		candidates = loaders
+	}
+
+	head, err := readHead(path)
+	if err != nil {
+		return Loader{}, err
+	}
+
+	for _, l := range candidates {
+		if l.Sniff != nil && l.Sniff(head) {
+			return l, nil
+		}
+	}
+
+	return Loader{}, fmt.Errorf("unknown track format of '%s'", path)
+}
+
+func readHead(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = f.Close() }()
+
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+
+	return head[:n], nil
+}
+
+// sniffXML returns a sniffer for XML documents with the given root element.
+func sniffXML(root string) func(head []byte) bool {
+	return func(head []byte) bool {
+		s := string(head)
+		return strings.Contains(s, "<"+root+" ") ||
+			strings.Contains(s, "<"+root+">") ||
+			strings.Contains(s, "<"+root+"\n") ||
+			strings.Contains(s, "<"+root+"\r")
+	}
+}
+
+// sniffNMEA recognizes sentences of the form `$TTSSS,...`.
+func sniffNMEA(head []byte) bool {
+	s := strings.TrimLeft(string(head), " \t\r\n")
+	return len(s) > 6 && s[0] == '$' && s[6] == ','
+}
+
+func sniffZIP(head []byte) bool {
+	return strings.HasPrefix(string(head), "PK\x03\x04")
+}
+
+func sniffGeoJSON(head []byte) bool {
+	s := strings.TrimLeft(string(head), " \t\r\n\ufeff")
+	return strings.HasPrefix(s, "{") && strings.Contains(s, `"type"`)
+}
@@ -0,0 +1,120 @@
+package geotrack
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadFormats(t *testing.T) {
+	tests := []struct {
+		file          string
+		wantTracks    int
+		wantSegments  int
+		wantPoints    int
+		wantWaypoints int
+		wantFirst     GPXPoint
+		wantLastTime  time.Time
+	}{
+		{
+			// Stopping the timer ends the first segment, the record without position
+			// is dropped.
+			file:         "testdata/activity.fit",
+			wantTracks:   1,
+			wantSegments: 2,
+			wantPoints:   4,
+			wantFirst:    GPXPoint{Lat: 47.5, Lon: 8.5, Time: time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)},
+			wantLastTime: time.Date(2024, 5, 1, 8, 10, 0, 0, time.UTC),
+		},
+		{
+			file:          "testdata/activity.tcx",
+			wantTracks:    2,
+			wantSegments:  3,
+			wantPoints:    4,
+			wantWaypoints: 1,
+			wantFirst:     GPXPoint{Lat: 47.5, Lon: 8.5, Time: time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)},
+			wantLastTime:  time.Date(2024, 5, 2, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			file:          "testdata/trip.kml",
+			wantTracks:    2,
+			wantSegments:  3,
+			wantPoints:    6,
+			wantWaypoints: 1,
+			wantFirst:     GPXPoint{Lat: 47.5, Lon: 8.5, Time: time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)},
+		},
+		{
+			file:          "testdata/trip.kmz",
+			wantTracks:    2,
+			wantSegments:  3,
+			wantPoints:    6,
+			wantWaypoints: 1,
+			wantFirst:     GPXPoint{Lat: 47.5, Lon: 8.5, Time: time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)},
+		},
+		{
+			file:          "testdata/trip.geojson",
+			wantTracks:    2,
+			wantSegments:  3,
+			wantPoints:    6,
+			wantWaypoints: 1,
+			wantFirst:     GPXPoint{Lat: 47.5, Lon: 8.5, Time: time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(filepath.Base(tt.file), func(t *testing.T) {
+			c, err := Load(tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			points := c.Points()
+
+			if len(c.Tracks) != tt.wantTracks || len(c.Segments()) != tt.wantSegments || len(points) != tt.wantPoints {
+				t.Fatalf("got %d tracks, %d segments and %d points, want %d, %d and %d",
+					len(c.Tracks), len(c.Segments()), len(points), tt.wantTracks, tt.wantSegments, tt.wantPoints)
+			}
+
+			if len(c.Waypoints) != tt.wantWaypoints {
+				t.Errorf("got %d waypoints, want %d", len(c.Waypoints), tt.wantWaypoints)
+			}
+
+			first := points[0]
+			if math.Abs(first.Lat-tt.wantFirst.Lat) > 1e-6 || math.Abs(first.Lon-tt.wantFirst.Lon) > 1e-6 || !first.Time.Equal(tt.wantFirst.Time) {
+				t.Errorf("first point %v %v at %s, want %v %v at %s",
+					first.Lat, first.Lon, first.Time, tt.wantFirst.Lat, tt.wantFirst.Lon, tt.wantFirst.Time)
+			}
+
+			if first.Elevation.IsNone() || math.Abs(first.Elevation.Get()-400) > 1e-6 {
+				t.Errorf("first point has elevation %v, want 400", first.Elevation)
+			}
+
+			if last := points[len(points)-1]; !last.Time.Equal(tt.wantLastTime) {
+				t.Errorf("last point at %s, want %s", last.Time, tt.wantLastTime)
+			}
+		})
+	}
+}
+
+func TestLoadSniffsFormat(t *testing.T) {
+	data, err := os.ReadFile("testdata/trip.geojson")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "trip.json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(c.Tracks) != 2 {
+		t.Errorf("got %d tracks, want 2", len(c.Tracks))
+	}
+}
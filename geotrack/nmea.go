@@ -2,52 +2,217 @@ package geotrack
 
 import (
 	"bufio"
+	"errors"
 	"os"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/adrianmo/go-nmea"
+	"github.com/bgraf/rueckblick/option"
 )
 
-func LoadNMEATrack(trackFilePath string) (points []GPXPoint, err error) {
+// Without a known date, two-digit RMC years below the pivot belong to the 21st century and
+// others to the 20th.
+const nmeaCenturyPivot = 80
+
+// FAA modes of RMC sentences denoting a usable position. Receivers older than NMEA 2.3
+// leave the mode empty.
+var nmeaValidFAAModes = []string{
+	"",
+	nmea.FAAModeAutonomous,
+	nmea.FAAModeDifferential,
+	nmea.FAAModePrecise,
+	nmea.FAAModeRTKInteger,
+	nmea.FAAModeRTKFloat,
+}
+
+// nmeaEpoch collects the sentences a receiver reports for one point in time.
+type nmeaEpoch struct {
+	time nmea.Time
+	rmc  *nmea.RMC
+	gga  *nmea.GGA
+}
+
+// nmeaReader merges RMC, GGA and ZDA sentences into points.
+type nmeaReader struct {
+	points  []GPXPoint
+	skipped int
+
+	epoch    *nmeaEpoch               // Epoch of the sentences read last
+	date     option.Option[time.Time] // Date of the most recent RMC or ZDA sentence
+	lastTime time.Duration            // Time of day of the most recent sentence with date
+}
+
+// LoadNMEA reads a log of NMEA sentences. Lines that cannot be parsed are skipped and
+// counted.
+func LoadNMEA(trackFilePath string) (*Collection, error) {
 	f, err := os.Open(trackFilePath)
 	if err != nil {
-		return
+		return nil, err
 	}
 
 	defer func() { _ = f.Close() }()
 
+	r := &nmeaReader{}
+
 	scanner := bufio.NewScanner(f)
 
 	for scanner.Scan() {
-		if scanner.Err() != nil {
-			return nil, scanner.Err()
+		r.readLine(scanner.Text())
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	r.flush()
+
+	c := NewSinglePathCollection(r.points)
+	c.Skipped = r.skipped
+
+	return c, nil
+}
+
+// LoadNMEATrack reads the points of a log of NMEA sentences.
+func LoadNMEATrack(trackFilePath string) (points []GPXPoint, err error) {
+	c, err := LoadNMEA(trackFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Points(), nil
+}
+
+func (r *nmeaReader) readLine(line string) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return
+	}
+
+	sentence, err := nmea.Parse(line)
+	if err != nil {
+		var notSupported *nmea.NotSupportedError
+		if !errors.As(err, &notSupported) {
+			r.skipped++
 		}
+		return
+	}
 
-		sentence, err := nmea.Parse(scanner.Text())
-		if err != nil {
-			return nil, err
+	switch s := sentence.(type) {
+	case nmea.RMC:
+		r.epochAt(s.Time).rmc = &s
+	case nmea.GGA:
+		r.epochAt(s.Time).gga = &s
+	case nmea.ZDA:
+		if s.Year > 0 && s.Time.Valid {
+			r.date = option.Some(time.Date(int(s.Year), time.Month(s.Month), int(s.Day), 0, 0, 0, 0, time.UTC))
+			r.lastTime = nmeaTimeOfDay(s.Time)
 		}
+	}
+}
+
+// epochAt returns the epoch of the given time, finishing the current epoch if it is of
+// another time.
+func (r *nmeaReader) epochAt(t nmea.Time) *nmeaEpoch {
+	if r.epoch != nil && r.epoch.time != t {
+		r.flush()
+	}
+
+	if r.epoch == nil {
+		r.epoch = &nmeaEpoch{time: t}
+	}
 
-		if sentence.DataType() == nmea.TypeRMC {
-			rmc := sentence.(nmea.RMC)
-			// We're only interested in "ACTIVE" status messages.
-			if rmc.FFAMode != "A" {
-				continue
-			}
-
-			// Adds 2000 to the date... I think this will be sufficient for life :)
-			date := time.Date(
-				2000+rmc.Date.YY, time.Month(rmc.Date.MM), rmc.Date.DD,
-				rmc.Time.Hour, rmc.Time.Minute, rmc.Time.Second, 0, time.UTC,
-			)
-
-			points = append(points, GPXPoint{
-				Lat:  rmc.Latitude,
-				Lon:  rmc.Longitude,
-				Time: date,
-			})
+	return r.epoch
+}
+
+// flush converts the current epoch into a point, if it denotes a valid position at a known
+// date.
+func (r *nmeaReader) flush() {
+	e := r.epoch
+	r.epoch = nil
+
+	if e == nil || !e.time.Valid {
+		return
+	}
+
+	timeOfDay := nmeaTimeOfDay(e.time)
+
+	if e.rmc != nil && e.rmc.Date.Valid {
+		r.date = option.Some(time.Date(r.fullYear(e.rmc.Date.YY), time.Month(e.rmc.Date.MM), e.rmc.Date.DD, 0, 0, 0, 0, time.UTC))
+	} else if r.date.IsSome() && timeOfDay < r.lastTime {
+		// Passed midnight since the date was reported.
+		r.date = option.Some(r.date.Get().AddDate(0, 0, 1))
+	}
+
+	if r.date.IsNone() {
+		return
+	}
+
+	r.lastTime = timeOfDay
+
+	var p GPXPoint
+
+	if e.rmc != nil {
+		if e.rmc.Validity != nmea.ValidRMC || !slices.Contains(nmeaValidFAAModes, e.rmc.FFAMode) {
+			return
+		}
+
+		p.Lat, p.Lon = e.rmc.Latitude, e.rmc.Longitude
+	}
+
+	if e.gga != nil {
+		if e.gga.FixQuality == "" || e.gga.FixQuality == nmea.Invalid {
+			return
 		}
+
+		if e.rmc == nil {
+			p.Lat, p.Lon = e.gga.Latitude, e.gga.Longitude
+		}
+
+		p.Elevation = option.Some(e.gga.Altitude)
+		p.Fix = e.gga.FixQuality
+		p.Satellites = option.Some(int(e.gga.NumSatellites))
+	}
+
+	p.Time = r.date.Get().Add(timeOfDay)
+
+	r.points = append(r.points, p)
+}
+
+// fullYear completes the two-digit year of an RMC sentence to the year closest to the
+// known date, e.g., one reported by a ZDA sentence with four-digit year.
+func (r *nmeaReader) fullYear(yy int) int {
+	if r.date.IsNone() {
+		if yy < nmeaCenturyPivot {
+			return 2000 + yy
+		}
+		return 1900 + yy
 	}
 
-	return
+	reference := r.date.Get().Year()
+	century := reference / 100 * 100
+
+	best := century + yy
+	for _, candidate := range []int{century - 100 + yy, century + 100 + yy} {
+		if abs(candidate-reference) < abs(best-reference) {
+			best = candidate
+		}
+	}
+
+	return best
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func nmeaTimeOfDay(t nmea.Time) time.Duration {
+	return time.Duration(t.Hour)*time.Hour +
+		time.Duration(t.Minute)*time.Minute +
+		time.Duration(t.Second)*time.Second +
+		time.Duration(t.Millisecond)*time.Millisecond
 }
@@ -1,6 +1,9 @@
 package geotrack
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func TestLoadNMEA(t *testing.T) {
 	_, err := LoadNMEATrack("testdata/nmea.txt")
@@ -8,3 +11,87 @@ func TestLoadNMEA(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestLoadNMEALogs(t *testing.T) {
+	type point struct {
+		time       time.Time
+		elevation  float64 // Zero if none
+		fix        string
+		satellites int
+	}
+
+	tests := []struct {
+		name        string
+		file        string
+		wantPoints  int
+		wantSkipped int
+		wantFirst   point
+		wantLast    point
+	}{
+		{
+			// GGA and RMC sentences of a modern receiver. The GGA sentence before the
+			// first RMC sentence has no date and is dropped.
+			name:       "merged RMC and GGA",
+			file:       "testdata/nmea.txt",
+			wantPoints: 100,
+			wantFirst:  point{time.Date(2023, 4, 2, 11, 4, 28, 240e6, time.UTC), 85.5, "1", 4},
+			wantLast:   point{time.Date(2023, 4, 2, 11, 6, 22, 0, time.UTC), 0, "", 0},
+		},
+		{
+			// RMC sentences without FAA mode of an NMEA 2.0 receiver from the last
+			// century, with a bad checksum, an invalid fix and an unparsable line.
+			name:        "legacy RMC with bad lines",
+			file:        "testdata/nmea_legacy.txt",
+			wantPoints:  3,
+			wantSkipped: 2,
+			wantFirst:   point{time.Date(1998, 12, 31, 23, 59, 58, 0, time.UTC), 0, "", 0},
+			wantLast:    point{time.Date(1999, 1, 1, 0, 0, 2, 0, time.UTC), 0, "", 0},
+		},
+		{
+			// The date of GGA sentences is taken from ZDA and advances at midnight. The
+			// century of the following RMC sentence follows the ZDA year.
+			name:       "GGA dated by ZDA",
+			file:       "testdata/nmea_zda.txt",
+			wantPoints: 3,
+			wantFirst:  point{time.Date(2099, 12, 31, 23, 59, 58, 0, time.UTC), 545.4, "1", 8},
+			wantLast:   point{time.Date(2100, 1, 1, 0, 0, 1, 0, time.UTC), 547.5, "2", 10},
+		},
+	}
+
+	toPoint := func(p GPXPoint) point {
+		q := point{time: p.Time, fix: p.Fix}
+		if p.Elevation.IsSome() {
+			q.elevation = p.Elevation.Get()
+		}
+		if p.Satellites.IsSome() {
+			q.satellites = p.Satellites.Get()
+		}
+		return q
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := LoadNMEA(tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			points := c.Points()
+			if len(points) != tt.wantPoints {
+				t.Fatalf("got %d points, want %d", len(points), tt.wantPoints)
+			}
+
+			if c.Skipped != tt.wantSkipped {
+				t.Errorf("skipped %d lines, want %d", c.Skipped, tt.wantSkipped)
+			}
+
+			if got := toPoint(points[0]); got != tt.wantFirst {
+				t.Errorf("first point %+v, want %+v", got, tt.wantFirst)
+			}
+
+			if got := toPoint(points[len(points)-1]); got != tt.wantLast {
+				t.Errorf("last point %+v, want %+v", got, tt.wantLast)
+			}
+		})
+	}
+}
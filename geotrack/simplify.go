@@ -0,0 +1,77 @@
+package geotrack
+
+import (
+	"math"
+	"slices"
+)
+
+// Mean radius of the earth in meters.
+const earthRadius = 6371008.8
+
+// Simplify reduces the points of a path with the Douglas-Peucker algorithm. No removed
+// point is farther than the tolerance in meters from the simplified path, and the first
+// and last points are always kept.
+func Simplify(points []GPXPoint, tolerance float64) []GPXPoint {
+	if len(points) < 3 || tolerance <= 0 {
+		return slices.Clone(points)
+	}
+
+	// Distances are computed in an equirectangular projection around the mean latitude,
+	// which is accurate enough for the extent of a track.
+	meanLat := 0.0
+	for _, p := range points {
+		meanLat += p.Lat
+	}
+	meanLat /= float64(len(points))
+
+	scaleX := earthRadius * math.Cos(meanLat*math.Pi/180) * math.Pi / 180
+	scaleY := earthRadius * math.Pi / 180
+
+	xy := make([][2]float64, len(points))
+	for i, p := range points {
+		xy[i] = [2]float64{p.Lon * scaleX, p.Lat * scaleY}
+	}
+
+	keep := make([]bool, len(points))
+	keep[0], keep[len(points)-1] = true, true
+
+	stack := [][2]int{{0, len(points) - 1}}
+	for len(stack) > 0 {
+		first, last := stack[len(stack)-1][0], stack[len(stack)-1][1]
+		stack = stack[:len(stack)-1]
+
+		maxDist, index := 0.0, -1
+		for i := first + 1; i < last; i++ {
+			if d := segmentDistance(xy[i], xy[first], xy[last]); d > maxDist {
+				maxDist, index = d, i
+			}
+		}
+
+		if index >= 0 && maxDist > tolerance {
+			keep[index] = true
+			stack = append(stack, [2]int{first, index}, [2]int{index, last})
+		}
+	}
+
+	var simplified []GPXPoint
+	for i, p := range points {
+		if keep[i] {
+			simplified = append(simplified, p)
+		}
+	}
+
+	return simplified
+}
+
+// segmentDistance returns the distance of p to the line segment from a to b.
+func segmentDistance(p, a, b [2]float64) float64 {
+	dx, dy := b[0]-a[0], b[1]-a[1]
+
+	t := 0.0
+	if l := dx*dx + dy*dy; l > 0 {
+		t = ((p[0]-a[0])*dx + (p[1]-a[1])*dy) / l
+		t = max(0, min(1, t))
+	}
+
+	return math.Hypot(p[0]-a[0]-t*dx, p[1]-a[1]-t*dy)
+}
@@ -0,0 +1,47 @@
+package geotrack
+
+import (
+	"testing"
+)
+
+func TestSimplify(t *testing.T) {
+	// A walk north with sideways deviations of about 7m and 3m, followed by a turn east.
+	// 0.0001° of latitude are about 11m.
+	points := []GPXPoint{
+		{Lat: 52.0000, Lon: 8.0000},
+		{Lat: 52.0010, Lon: 8.0001},
+		{Lat: 52.0020, Lon: 8.0000},
+		{Lat: 52.0030, Lon: 8.0000},
+		{Lat: 52.0030, Lon: 8.0020},
+	}
+
+	tests := []struct {
+		name      string
+		points    []GPXPoint
+		tolerance float64
+		want      []int // Indices of the kept points
+	}{
+		{"empty", nil, 10, nil},
+		{"two points", points[:2], 10, []int{0, 1}},
+		{"no tolerance", points, 0, []int{0, 1, 2, 3, 4}},
+		{"below deviation", points, 5, []int{0, 1, 3, 4}},
+		{"above deviation", points, 10, []int{0, 3, 4}},
+		{"above corner", points, 200, []int{0, 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Simplify(tt.points, tt.tolerance)
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d points %v, want %d", len(got), got, len(tt.want))
+			}
+
+			for i, index := range tt.want {
+				if got[i] != tt.points[index] {
+					t.Errorf("point %d is %v, want %v", i, got[i], tt.points[index])
+				}
+			}
+		})
+	}
+}
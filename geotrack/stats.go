@@ -0,0 +1,140 @@
+package geotrack
+
+import (
+	"time"
+
+	"github.com/jftuga/geodist"
+)
+
+// Minimal speed in m/s between two points to count the time between them as moving time.
+const movingSpeedThreshold = 0.5
+
+// Minimal change of elevation in meters to count as ascent or descent. Smaller changes are
+// considered noise of the elevation measurement.
+const elevationHysteresis = 5.0
+
+// Statistics summarizes a track.
+type Statistics struct {
+	Distance     float64       // Meters
+	Duration     time.Duration // Between the first and the last point
+	MovingTime   time.Duration // Time spent moving faster than a walking crawl
+	MaxSpeed     float64       // Meters per second
+	HasElevation bool          // Whether the following elevation fields are valid
+	Ascent       float64       // Meters
+	Descent      float64       // Meters
+	MinElevation float64       // Meters above sea level
+	MaxElevation float64       // Meters above sea level
+}
+
+// AverageSpeed returns the distance divided by the duration in meters per second.
+func (s Statistics) AverageSpeed() float64 {
+	if s.Duration <= 0 {
+		return 0
+	}
+
+	return s.Distance / s.Duration.Seconds()
+}
+
+// MovingSpeed returns the distance divided by the moving time in meters per second.
+func (s Statistics) MovingSpeed() float64 {
+	if s.MovingTime <= 0 {
+		return 0
+	}
+
+	return s.Distance / s.MovingTime.Seconds()
+}
+
+// Distance returns the great-circle distance between the points in meters.
+func Distance(p, q GPXPoint) float64 {
+	_, km := geodist.HaversineDistance(
+		geodist.Coord{Lat: p.Lat, Lon: p.Lon},
+		geodist.Coord{Lat: q.Lat, Lon: q.Lon},
+	)
+
+	return km * 1000
+}
+
+// ComputeStatistics computes the statistics of the track given by its points.
+func ComputeStatistics(points []GPXPoint) Statistics {
+	var s Statistics
+
+	if len(points) == 0 {
+		return s
+	}
+
+	if !points[0].Time.IsZero() && !points[len(points)-1].Time.IsZero() {
+		s.Duration = points[len(points)-1].Time.Sub(points[0].Time)
+	}
+
+	for i := 1; i < len(points); i++ {
+		d := Distance(points[i-1], points[i])
+		s.Distance += d
+
+		dt := points[i].Time.Sub(points[i-1].Time)
+		if points[i-1].Time.IsZero() || points[i].Time.IsZero() || dt <= 0 {
+			continue
+		}
+
+		speed := d / dt.Seconds()
+		if speed >= movingSpeedThreshold {
+			s.MovingTime += dt
+		}
+
+		s.MaxSpeed = max(s.MaxSpeed, speed)
+	}
+
+	var reference float64
+
+	for _, p := range points {
+		if p.Elevation.IsNone() {
+			continue
+		}
+
+		elevation := p.Elevation.Get()
+
+		if !s.HasElevation {
+			s.HasElevation = true
+			s.MinElevation, s.MaxElevation = elevation, elevation
+			reference = elevation
+			continue
+		}
+
+		s.MinElevation = min(s.MinElevation, elevation)
+		s.MaxElevation = max(s.MaxElevation, elevation)
+
+		if diff := elevation - reference; diff >= elevationHysteresis {
+			s.Ascent += diff
+			reference = elevation
+		} else if -diff >= elevationHysteresis {
+			s.Descent -= diff
+			reference = elevation
+		}
+	}
+
+	return s
+}
+
+// ProfilePoint is a point of an elevation profile.
+type ProfilePoint struct {
+	Distance  float64 // Meters from the start of the track
+	Elevation float64 // Meters above sea level
+}
+
+// ElevationProfile returns the elevation over the distance travelled for all points with
+// elevation.
+func ElevationProfile(points []GPXPoint) []ProfilePoint {
+	var profile []ProfilePoint
+
+	distance := 0.0
+	for i, p := range points {
+		if i > 0 {
+			distance += Distance(points[i-1], p)
+		}
+
+		if p.Elevation.IsSome() {
+			profile = append(profile, ProfilePoint{Distance: distance, Elevation: p.Elevation.Get()})
+		}
+	}
+
+	return profile
+}
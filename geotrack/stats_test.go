@@ -0,0 +1,57 @@
+package geotrack
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestComputeStatistics(t *testing.T) {
+	points, err := LoadGPXTrack("testdata/elevation.gpx")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(points) != 5 || points[0].Elevation.IsNone() || points[0].Elevation.Get() != 100 {
+		t.Fatalf("elevation not loaded: %+v", points)
+	}
+
+	s := ComputeStatistics(points)
+
+	// Three steps of 0.001° latitude, about 111.2m each.
+	if math.Abs(s.Distance-333.6) > 1 {
+		t.Errorf("distance %f, want about 333.6", s.Distance)
+	}
+
+	if s.Duration != 13*time.Minute {
+		t.Errorf("duration %s, want 13m", s.Duration)
+	}
+
+	// The ten minutes standing still do not count.
+	if s.MovingTime != 3*time.Minute {
+		t.Errorf("moving time %s, want 3m", s.MovingTime)
+	}
+
+	// 100 -> 102 is below the hysteresis, 100 -> 120 is counted, 120 -> 121 is not,
+	// 120 -> 110 is.
+	if !s.HasElevation || s.Ascent != 20 || s.Descent != 10 {
+		t.Errorf("ascent %f, descent %f, want 20 and 10", s.Ascent, s.Descent)
+	}
+
+	if s.MinElevation != 100 || s.MaxElevation != 121 {
+		t.Errorf("elevation range %f - %f, want 100 - 121", s.MinElevation, s.MaxElevation)
+	}
+
+	profile := ElevationProfile(points)
+	if len(profile) != 5 || profile[0].Distance != 0 || math.Abs(profile[4].Distance-s.Distance) > 1e-6 {
+		t.Errorf("unexpected profile %+v", profile)
+	}
+}
+
+func TestComputeStatisticsWithoutTimeAndElevation(t *testing.T) {
+	s := ComputeStatistics([]GPXPoint{{Lat: 52, Lon: 8}, {Lat: 52, Lon: 8.001}})
+
+	if s.Distance <= 0 || s.Duration != 0 || s.MovingTime != 0 || s.HasElevation {
+		t.Errorf("unexpected statistics %+v", s)
+	}
+}
@@ -0,0 +1,134 @@
+package geotrack
+
+import (
+	"encoding/xml"
+	"os"
+	"time"
+
+	"github.com/bgraf/rueckblick/option"
+)
+
+type tcxDatabase struct {
+	Activities []tcxActivity `xml:"Activities>Activity"`
+	Courses    []tcxCourse   `xml:"Courses>Course"`
+}
+
+type tcxActivity struct {
+	Sport string   `xml:"Sport,attr"`
+	Laps  []tcxLap `xml:"Lap"`
+}
+
+type tcxLap struct {
+	Tracks []tcxTrack `xml:"Track"`
+}
+
+type tcxCourse struct {
+	Name        string           `xml:"Name"`
+	Tracks      []tcxTrack       `xml:"Track"`
+	CoursePoint []tcxCoursePoint `xml:"CoursePoint"`
+}
+
+type tcxTrack struct {
+	Points []tcxPoint `xml:"Trackpoint"`
+}
+
+type tcxPoint struct {
+	Time     time.Time    `xml:"Time"`
+	Position *tcxPosition `xml:"Position"`
+	Altitude *float64     `xml:"AltitudeMeters"`
+}
+
+type tcxPosition struct {
+	Lat float64 `xml:"LatitudeDegrees"`
+	Lon float64 `xml:"LongitudeDegrees"`
+}
+
+type tcxCoursePoint struct {
+	tcxPoint
+	Name  string `xml:"Name"`
+	Type  string `xml:"PointType"`
+	Notes string `xml:"Notes"`
+}
+
+// LoadTCX reads the activities and courses of a Training Center XML file. Every lap of
+// an activity is a segment of its track, and course points become waypoints.
+func LoadTCX(trackFilePath string) (*Collection, error) {
+	f, err := os.Open(trackFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = f.Close() }()
+
+	var db tcxDatabase
+	if err := xml.NewDecoder(f).Decode(&db); err != nil {
+		return nil, err
+	}
+
+	c := &Collection{}
+
+	for _, activity := range db.Activities {
+		t := Track{Name: activity.Sport}
+
+		for _, lap := range activity.Laps {
+			t.Segments = append(t.Segments, convertTCXTracks(lap.Tracks)...)
+		}
+
+		c.Tracks = append(c.Tracks, t)
+	}
+
+	for _, course := range db.Courses {
+		c.Tracks = append(c.Tracks, Track{Name: course.Name, Segments: convertTCXTracks(course.Tracks)})
+
+		for _, p := range course.CoursePoint {
+			point, ok := convertTCXPoint(p.tcxPoint)
+			if !ok {
+				continue
+			}
+
+			name := p.Name
+			if name == "" {
+				name = p.Type
+			}
+
+			c.Waypoints = append(c.Waypoints, Waypoint{Point: point, Name: name, Description: p.Notes})
+		}
+	}
+
+	return c, nil
+}
+
+func convertTCXTracks(tracks []tcxTrack) []Segment {
+	var segments []Segment
+
+	for _, track := range tracks {
+		var points []GPXPoint
+
+		for _, p := range track.Points {
+			if point, ok := convertTCXPoint(p); ok {
+				points = append(points, point)
+			}
+		}
+
+		if len(points) > 0 {
+			segments = append(segments, Segment{Points: points})
+		}
+	}
+
+	return segments
+}
+
+// convertTCXPoint converts a trackpoint, which lacks a position when the device had no
+// fix or only recorded sensor data.
+func convertTCXPoint(p tcxPoint) (GPXPoint, bool) {
+	if p.Position == nil {
+		return GPXPoint{}, false
+	}
+
+	point := GPXPoint{Lat: p.Position.Lat, Lon: p.Position.Lon, Time: p.Time.UTC()}
+	if p.Altitude != nil {
+		point.Elevation = option.Some(*p.Altitude)
+	}
+
+	return point, true
+}
@@ -3,11 +3,16 @@ package geotrack
 import (
 	"encoding/json"
 	"time"
+
+	"github.com/bgraf/rueckblick/option"
 )
 
 type GPXPoint struct {
-	Lat, Lon float64
-	Time     time.Time
+	Lat, Lon   float64
+	Time       time.Time
+	Elevation  option.Option[float64] // Meters above sea level
+	Fix        string                 // Type or quality of the GPS fix as reported by the receiver
+	Satellites option.Option[int]     // Number of satellites used for the fix
 }
 
 func (p GPXPoint) MarshalJSON() ([]byte, error) {
@@ -0,0 +1,477 @@
+package images
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Maximal size of the metadata read from an image, which guards against corrupt lengths.
+const maxMetadataSize = 16 << 20
+
+var (
+	jpegMagic = []byte{0xff, 0xd8}
+	pngMagic  = []byte("\x89PNG\r\n\x1a\n")
+	exifMagic = []byte("Exif\x00\x00")
+)
+
+// extractTIFF locates the TIFF structure holding the EXIF data within a JPEG, PNG, or
+// HEIC/HEIF file.
+func extractTIFF(r io.ReadSeeker) ([]byte, error) {
+	header := make([]byte, 12)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, ErrNoExif
+	}
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case bytes.HasPrefix(header, jpegMagic):
+		return extractTIFFFromJPEG(r)
+	case bytes.HasPrefix(header, pngMagic):
+		return extractTIFFFromPNG(r)
+	case string(header[4:8]) == "ftyp":
+		return extractTIFFFromHEIF(r)
+	}
+
+	return nil, fmt.Errorf("unsupported image format")
+}
+
+// readPayload reads length bytes. The buffer grows with the bytes actually read, such that a
+// length beyond the end of a truncated file does not allocate it upfront.
+func readPayload(r io.Reader, length uint64) ([]byte, error) {
+	if length > maxMetadataSize {
+		return nil, fmt.Errorf("metadata of %d bytes exceeds limit", length)
+	}
+
+	payload, err := io.ReadAll(io.LimitReader(r, int64(length)))
+	if err != nil {
+		return nil, err
+	}
+
+	if uint64(len(payload)) < length {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	return payload, nil
+}
+
+// jpegSegment is a marker segment of a JPEG file preceding the image data.
+type jpegSegment struct {
+	Marker  byte
+	Payload []byte
+}
+
+// readJPEGSegments reads all marker segments up to the start of the scan data. The
+// returned offset is the position of the start-of-scan marker.
+func readJPEGSegments(r io.Reader, keep func(marker byte) bool) ([]jpegSegment, int64, error) {
+	br := bufio.NewReader(r)
+
+	soi := make([]byte, 2)
+	if _, err := io.ReadFull(br, soi); err != nil || !bytes.Equal(soi, jpegMagic) {
+		return nil, 0, fmt.Errorf("not a JPEG file")
+	}
+
+	offset := int64(2)
+	var segments []jpegSegment
+
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return segments, offset, err
+		}
+		if b != 0xff {
+			return segments, offset, fmt.Errorf("invalid JPEG marker")
+		}
+
+		marker, err := br.ReadByte()
+		if err != nil {
+			return segments, offset, err
+		}
+
+		// Fill bytes
+		for marker == 0xff {
+			offset++
+			if marker, err = br.ReadByte(); err != nil {
+				return segments, offset, err
+			}
+		}
+
+		switch {
+		case marker == 0xda || marker == 0xd9:
+			// Start of scan or end of image
+			return segments, offset, nil
+		case marker == 0x01 || (marker >= 0xd0 && marker <= 0xd7):
+			// Stand-alone markers
+			offset += 2
+			continue
+		}
+
+		lengthBytes := make([]byte, 2)
+		if _, err := io.ReadFull(br, lengthBytes); err != nil {
+			return segments, offset, err
+		}
+
+		length := int(binary.BigEndian.Uint16(lengthBytes))
+		if length < 2 {
+			return segments, offset, fmt.Errorf("invalid JPEG segment length")
+		}
+
+		if keep(marker) {
+			payload := make([]byte, length-2)
+			if _, err := io.ReadFull(br, payload); err != nil {
+				return segments, offset, err
+			}
+			segments = append(segments, jpegSegment{Marker: marker, Payload: payload})
+		} else if _, err := br.Discard(length - 2); err != nil {
+			return segments, offset, err
+		}
+
+		offset += int64(2 + length)
+	}
+}
+
+func isExifSegment(s jpegSegment) bool {
+	return s.Marker == 0xe1 && bytes.HasPrefix(s.Payload, exifMagic)
+}
+
+func extractTIFFFromJPEG(r io.Reader) ([]byte, error) {
+	segments, _, err := readJPEGSegments(r, func(marker byte) bool { return marker == 0xe1 })
+
+	for _, s := range segments {
+		if isExifSegment(s) {
+			return s.Payload[len(exifMagic):], nil
+		}
+	}
+
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	return nil, ErrNoExif
+}
+
+func extractTIFFFromPNG(r io.Reader) ([]byte, error) {
+	br := bufio.NewReader(r)
+
+	if _, err := br.Discard(len(pngMagic)); err != nil {
+		return nil, err
+	}
+
+	chunkHeader := make([]byte, 8)
+
+	for {
+		if _, err := io.ReadFull(br, chunkHeader); err != nil {
+			return nil, ErrNoExif
+		}
+
+		length := int(binary.BigEndian.Uint32(chunkHeader[:4]))
+		chunkType := string(chunkHeader[4:8])
+
+		switch chunkType {
+		case "eXIf":
+			payload, err := readPayload(br, uint64(length))
+			if err != nil {
+				return nil, err
+			}
+			// Some writers include the JPEG identifier code.
+			return bytes.TrimPrefix(payload, exifMagic), nil
+		case "IEND":
+			return nil, ErrNoExif
+		}
+
+		// Skip chunk data and CRC
+		if _, err := br.Discard(length + 4); err != nil {
+			return nil, ErrNoExif
+		}
+	}
+}
+
+// isobmffBox is a box of the ISO base media file format used by HEIC/HEIF files.
+type isobmffBox struct {
+	Type    string
+	Payload []byte
+}
+
+// readBoxes splits a byte slice into consecutive boxes.
+func readBoxes(b []byte) ([]isobmffBox, error) {
+	var boxes []isobmffBox
+
+	for len(b) >= 8 {
+		size := uint64(binary.BigEndian.Uint32(b[:4]))
+		boxType := string(b[4:8])
+		headerSize := uint64(8)
+
+		switch size {
+		case 0:
+			size = uint64(len(b))
+		case 1:
+			if len(b) < 16 {
+				return boxes, errInvalidHEIF
+			}
+			size = binary.BigEndian.Uint64(b[8:16])
+			headerSize = 16
+		}
+
+		if size < headerSize || size > uint64(len(b)) {
+			return boxes, errInvalidHEIF
+		}
+
+		boxes = append(boxes, isobmffBox{Type: boxType, Payload: b[headerSize:size]})
+		b = b[size:]
+	}
+
+	return boxes, nil
+}
+
+var errInvalidHEIF = errors.New("invalid HEIF structure")
+
+// findTopLevelBox scans the top-level boxes of the file for a box of the given type and
+// returns its payload.
+func findTopLevelBox(r io.ReadSeeker, boxType string) ([]byte, error) {
+	header := make([]byte, 16)
+	offset := int64(0)
+
+	for {
+		if _, err := r.Seek(offset, io.SeekStart); err != nil {
+			return nil, err
+		}
+
+		if _, err := io.ReadFull(r, header[:8]); err != nil {
+			return nil, ErrNoExif
+		}
+
+		size := int64(binary.BigEndian.Uint32(header[:4]))
+		headerSize := int64(8)
+
+		if size == 1 {
+			if _, err := io.ReadFull(r, header[8:16]); err != nil {
+				return nil, errInvalidHEIF
+			}
+			size = int64(binary.BigEndian.Uint64(header[8:16]))
+			headerSize = 16
+		}
+
+		if string(header[4:8]) == boxType {
+			if size == 0 {
+				return io.ReadAll(io.LimitReader(r, maxMetadataSize))
+			}
+			if size < headerSize {
+				return nil, errInvalidHEIF
+			}
+			return readPayload(r, uint64(size-headerSize))
+		}
+
+		if size < headerSize {
+			return nil, ErrNoExif
+		}
+
+		offset += size
+	}
+}
+
+// extractTIFFFromHEIF resolves the `Exif` item of the `meta` box via the item location box.
+func extractTIFFFromHEIF(r io.ReadSeeker) ([]byte, error) {
+	meta, err := findTopLevelBox(r, "meta")
+	if err != nil {
+		return nil, err
+	}
+
+	// `meta` is a full box, skip version and flags.
+	if len(meta) < 4 {
+		return nil, errInvalidHEIF
+	}
+
+	boxes, err := readBoxes(meta[4:])
+	if err != nil {
+		return nil, err
+	}
+
+	var exifItemID uint32
+	var hasExifItem bool
+	var iloc []byte
+
+	for _, box := range boxes {
+		switch box.Type {
+		case "iinf":
+			exifItemID, hasExifItem, err = findExifItemID(box.Payload)
+			if err != nil {
+				return nil, err
+			}
+		case "iloc":
+			iloc = box.Payload
+		}
+	}
+
+	if !hasExifItem || iloc == nil {
+		return nil, ErrNoExif
+	}
+
+	offset, length, err := findItemLocation(iloc, exifItemID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := r.Seek(int64(offset), io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	payload, err := readPayload(r, length)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(payload) < 4 {
+		return nil, errInvalidHEIF
+	}
+
+	headerOffset := uint64(binary.BigEndian.Uint32(payload[:4]))
+	if 4+headerOffset > uint64(len(payload)) {
+		return nil, errInvalidHEIF
+	}
+
+	return bytes.TrimPrefix(payload[4+headerOffset:], exifMagic), nil
+}
+
+func findExifItemID(iinf []byte) (uint32, bool, error) {
+	if len(iinf) < 6 {
+		return 0, false, errInvalidHEIF
+	}
+
+	pos := 6
+	if iinf[0] != 0 {
+		pos = 8
+	}
+
+	if pos > len(iinf) {
+		return 0, false, errInvalidHEIF
+	}
+
+	entries, err := readBoxes(iinf[pos:])
+	if err != nil {
+		return 0, false, err
+	}
+
+	for _, entry := range entries {
+		p := entry.Payload
+		if entry.Type != "infe" || len(p) < 4 {
+			continue
+		}
+
+		version := p[0]
+		var itemID uint32
+		var itemType string
+
+		switch {
+		case version == 2 && len(p) >= 12:
+			itemID = uint32(binary.BigEndian.Uint16(p[4:6]))
+			itemType = string(p[8:12])
+		case version == 3 && len(p) >= 14:
+			itemID = binary.BigEndian.Uint32(p[4:8])
+			itemType = string(p[10:14])
+		default:
+			continue
+		}
+
+		if itemType == "Exif" {
+			return itemID, true, nil
+		}
+	}
+
+	return 0, false, nil
+}
+
+func findItemLocation(iloc []byte, itemID uint32) (offset uint64, length uint64, err error) {
+	pos := 0
+
+	read := func(n int) (uint64, bool) {
+		if n == 0 {
+			return 0, true
+		}
+		if pos+n > len(iloc) {
+			return 0, false
+		}
+
+		var v uint64
+		for _, b := range iloc[pos : pos+n] {
+			v = v<<8 | uint64(b)
+		}
+		pos += n
+
+		return v, true
+	}
+
+	versionFlags, ok := read(4)
+	if !ok {
+		return 0, 0, errInvalidHEIF
+	}
+	version := versionFlags >> 24
+
+	sizes, ok := read(2)
+	if !ok {
+		return 0, 0, errInvalidHEIF
+	}
+
+	offsetSize := int(sizes>>12) & 0xf
+	lengthSize := int(sizes>>8) & 0xf
+	baseOffsetSize := int(sizes>>4) & 0xf
+	indexSize := 0
+	if version == 1 || version == 2 {
+		indexSize = int(sizes) & 0xf
+	}
+
+	idSize := 2
+	if version == 2 {
+		idSize = 4
+	}
+
+	itemCount, ok := read(idSize)
+	if !ok {
+		return 0, 0, errInvalidHEIF
+	}
+
+	for range itemCount {
+		id, ok1 := read(idSize)
+
+		if version == 1 || version == 2 {
+			if _, ok := read(2); !ok {
+				return 0, 0, errInvalidHEIF
+			}
+		}
+
+		_, ok2 := read(2) // data reference index
+		baseOffset, ok3 := read(baseOffsetSize)
+		extentCount, ok4 := read(2)
+
+		if !ok1 || !ok2 || !ok3 || !ok4 {
+			return 0, 0, errInvalidHEIF
+		}
+
+		var extentOffset, extentLength uint64
+		for i := range extentCount {
+			_, ok1 := read(indexSize)
+			o, ok2 := read(offsetSize)
+			l, ok3 := read(lengthSize)
+
+			if !ok1 || !ok2 || !ok3 {
+				return 0, 0, errInvalidHEIF
+			}
+
+			// Exif items are expected to consist of a single extent.
+			if i == 0 {
+				extentOffset, extentLength = o, l
+			}
+		}
+
+		if uint32(id) == itemID {
+			return baseOffset + extentOffset, extentLength, nil
+		}
+	}
+
+	return 0, 0, ErrNoExif
+}
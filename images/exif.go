@@ -1,10 +1,11 @@
 package images
 
 import (
-	"bytes"
-	"encoding/json"
 	"errors"
-	"os/exec"
+	"fmt"
+	"io"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/bgraf/rueckblick/geotrack"
@@ -14,73 +15,112 @@ import (
 var ErrNoExif = errors.New("no EXIF data")
 
 type EXIFData struct {
-	Time   option.Option[time.Time]
-	LatLon option.Option[geotrack.GPXPoint]
+	// Time the photo was taken. Located in the zone given by `TimeOffset`, or in the
+	// local time zone if the camera did not record an offset.
+	Time       option.Option[time.Time]
+	TimeOffset option.Option[time.Duration]
+	LatLon     option.Option[geotrack.GPXPoint]
+	Altitude   option.Option[float64] // Meters above sea level
+
+	Orientation int // EXIF orientation from 1 to 8, zero if unknown
+	Make        string
+	Model       string
+	Description string
 }
 
+// ReadEXIFFromFile reads the EXIF data of a JPEG, PNG, or HEIC/HEIF image.
 func ReadEXIFFromFile(path string) (EXIFData, error) {
-	command := exec.Command(
-		"exiftool",
-		"-json",
-		"-n",
-		"-DateTimeOriginal",
-		"-GPSLatitude",
-		"-GPSLatitudeRef",
-		"-GPSLongitude",
-		"-GPSLongitudeRef",
-		path,
-	)
-	var buffer bytes.Buffer
-	command.Stdout = &buffer
-	if err := command.Run(); err != nil {
-		return EXIFData{}, ErrNoExif
+	f, err := os.Open(path)
+	if err != nil {
+		return EXIFData{}, err
 	}
 
-	var rawExifData []struct {
-		DateTimeOriginal string  `json:"DateTimeOriginal"`
-		Lat              float64 `json:"GPSLatitude"`
-		Lon              float64 `json:"GPSLongitude"`
-	}
+	defer func() { _ = f.Close() }()
 
-	if err := json.Unmarshal(buffer.Bytes(), &rawExifData); err != nil {
+	return ReadEXIF(f)
+}
+
+// ReadEXIF reads the EXIF data of a JPEG, PNG, or HEIC/HEIF image.
+func ReadEXIF(r io.ReadSeeker) (EXIFData, error) {
+	tiff, err := extractTIFF(r)
+	if err != nil {
 		return EXIFData{}, err
 	}
 
-	datetime := option.None[time.Time]()
-	if len(rawExifData[0].DateTimeOriginal) > 0 {
-		dateTimeOriginal, err := time.Parse("2006:01:02 15:04:05", rawExifData[0].DateTimeOriginal)
-		if err == nil {
-			datetime = option.Some(time.Date(
-				dateTimeOriginal.Year(),
-				dateTimeOriginal.Month(),
-				dateTimeOriginal.Day(),
-				dateTimeOriginal.Hour(),
-				dateTimeOriginal.Minute(),
-				dateTimeOriginal.Second(),
-				dateTimeOriginal.Nanosecond(),
-				time.Local,
-			))
-			//return nil, err
+	t, err := parseTIFF(tiff)
+	if err != nil {
+		return EXIFData{}, fmt.Errorf("parse EXIF: %w", err)
+	}
+
+	return t.exifData(), nil
+}
+
+func (t *tiffData) exifData() EXIFData {
+	data := EXIFData{
+		Time:       option.None[time.Time](),
+		TimeOffset: option.None[time.Duration](),
+		LatLon:     option.None[geotrack.GPXPoint](),
+		Altitude:   option.None[float64](),
+	}
+
+	if orientation, ok := t.uint(t.ifd0, tagOrientation); ok && orientation >= 1 && orientation <= 8 {
+		data.Orientation = int(orientation)
+	}
+
+	data.Make, _ = t.string(t.ifd0, tagMake)
+	data.Model, _ = t.string(t.ifd0, tagModel)
+	data.Description, _ = t.string(t.ifd0, tagImageDescription)
+
+	location := time.Local
+	if s, ok := t.string(t.exif, tagOffsetTimeOriginal); ok {
+		if offset, err := parseTimeOffset(s); err == nil {
+			data.TimeOffset = option.Some(offset)
+			location = time.FixedZone(s, int(offset.Seconds()))
 		}
+	}
 
+	if s, ok := t.string(t.exif, tagDateTimeOriginal); ok {
+		if dateTimeOriginal, err := time.ParseInLocation("2006:01:02 15:04:05", s, location); err == nil {
+			data.Time = option.Some(dateTimeOriginal)
+		}
 	}
 
-	latlon := option.None[geotrack.GPXPoint]()
-	if rawExifData[0].Lat != 0 && rawExifData[0].Lon != 0 {
-		t := time.Time{}
-		if datetime.IsSome() {
-			t = datetime.Get()
+	lat, okLat := t.coordinate(tagGPSLatitude, tagGPSLatitudeRef, "S")
+	lon, okLon := t.coordinate(tagGPSLongitude, tagGPSLongitudeRef, "W")
+	if okLat && okLon && (lat != 0 || lon != 0) {
+		p := geotrack.GPXPoint{Lat: lat, Lon: lon}
+		if data.Time.IsSome() {
+			p.Time = data.Time.Get()
 		}
-		latlon = option.Some(geotrack.GPXPoint{
-			Lat:  rawExifData[0].Lat,
-			Lon:  rawExifData[0].Lon,
-			Time: t,
-		})
+		data.LatLon = option.Some(p)
 	}
 
-	return EXIFData{
-		Time:   datetime,
-		LatLon: latlon,
-	}, nil
+	if alt, ok := t.rationals(t.gps, tagGPSAltitude); ok && len(alt) > 0 {
+		// Reference 1 denotes heights below sea level.
+		if ref, ok := t.uint(t.gps, tagGPSAltitudeRef); ok && ref == 1 {
+			alt[0] = -alt[0]
+		}
+		data.Altitude = option.Some(alt[0])
+	}
+
+	return data
+}
+
+// parseTimeOffset parses EXIF time offsets of the form `+HH:MM`.
+func parseTimeOffset(s string) (time.Duration, error) {
+	if len(s) != 6 || (s[0] != '+' && s[0] != '-') || s[3] != ':' {
+		return 0, fmt.Errorf("invalid time offset '%s'", s)
+	}
+
+	t, err := time.Parse("15:04", s[1:])
+	if err != nil {
+		return 0, fmt.Errorf("invalid time offset '%s'", s)
+	}
+
+	offset := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	if strings.HasPrefix(s, "-") {
+		offset = -offset
+	}
 
+	return offset, nil
 }
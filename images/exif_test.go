@@ -0,0 +1,155 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestReadEXIFFromFile(t *testing.T) {
+	cest := time.FixedZone("+02:00", 2*60*60)
+
+	tests := []struct {
+		file        string
+		time        time.Time
+		offset      time.Duration
+		hasOffset   bool
+		lat, lon    float64
+		altitude    float64
+		orientation int
+		model       string
+		description string
+	}{
+		{
+			file:        "testdata/exif.jpg",
+			time:        time.Date(2023, 4, 2, 13, 5, 0, 0, cest),
+			offset:      2 * time.Hour,
+			hasOffset:   true,
+			lat:         52.0 + 15.0/60 + 48.81/3600,
+			lon:         8.0 + 2.0/60 + 36.12/3600,
+			altitude:    89.5,
+			orientation: 6,
+			model:       "ACME Camera 3000",
+			description: "Sunset at the lake",
+		},
+		{
+			file:        "testdata/exif_bigendian.jpg",
+			time:        time.Date(2021, 12, 24, 18, 30, 15, 0, time.Local),
+			lat:         -(33.0 + 51.0/60 + 35.4/3600),
+			lon:         -(70.0 + 39.0/60),
+			altitude:    -12,
+			orientation: 1,
+			model:       "Big Endian Cam",
+		},
+		{
+			file:        "testdata/exif.png",
+			time:        time.Date(2023, 4, 2, 13, 5, 0, 0, cest),
+			offset:      2 * time.Hour,
+			hasOffset:   true,
+			lat:         52.0 + 15.0/60 + 48.81/3600,
+			lon:         8.0 + 2.0/60 + 36.12/3600,
+			altitude:    89.5,
+			orientation: 6,
+			model:       "ACME Camera 3000",
+			description: "Sunset at the lake",
+		},
+		{
+			file:        "testdata/exif.heic",
+			time:        time.Date(2023, 4, 2, 13, 5, 0, 0, cest),
+			offset:      2 * time.Hour,
+			hasOffset:   true,
+			lat:         52.0 + 15.0/60 + 48.81/3600,
+			lon:         8.0 + 2.0/60 + 36.12/3600,
+			altitude:    89.5,
+			orientation: 6,
+			model:       "ACME Camera 3000",
+			description: "Sunset at the lake",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := ReadEXIFFromFile(tt.file)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if data.Time.IsNone() || !data.Time.Get().Equal(tt.time) {
+				t.Errorf("time: got %v, want %v", data.Time, tt.time)
+			}
+
+			if data.TimeOffset.IsSome() != tt.hasOffset {
+				t.Errorf("time offset: got %v, want present=%v", data.TimeOffset, tt.hasOffset)
+			} else if tt.hasOffset && data.TimeOffset.Get() != tt.offset {
+				t.Errorf("time offset: got %s, want %s", data.TimeOffset.Get(), tt.offset)
+			}
+
+			if data.LatLon.IsNone() {
+				t.Fatalf("missing position")
+			}
+
+			p := data.LatLon.Get()
+			if math.Abs(p.Lat-tt.lat) > 1e-9 || math.Abs(p.Lon-tt.lon) > 1e-9 {
+				t.Errorf("position: got %f,%f, want %f,%f", p.Lat, p.Lon, tt.lat, tt.lon)
+			}
+
+			if data.Altitude.IsNone() || math.Abs(data.Altitude.Get()-tt.altitude) > 1e-9 {
+				t.Errorf("altitude: got %v, want %f", data.Altitude, tt.altitude)
+			}
+
+			if data.Orientation != tt.orientation {
+				t.Errorf("orientation: got %d, want %d", data.Orientation, tt.orientation)
+			}
+
+			if data.Model != tt.model {
+				t.Errorf("model: got %q, want %q", data.Model, tt.model)
+			}
+
+			if data.Description != tt.description {
+				t.Errorf("description: got %q, want %q", data.Description, tt.description)
+			}
+		})
+	}
+}
+
+func TestReadEXIFFromFileWithoutEXIF(t *testing.T) {
+	_, err := ReadEXIFFromFile("testdata/noexif.jpg")
+	if !errors.Is(err, ErrNoExif) {
+		t.Errorf("expected ErrNoExif, got %v", err)
+	}
+}
+
+func TestReadEXIFTruncated(t *testing.T) {
+	heic, err := os.ReadFile("testdata/exif.heic")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// PNG with an eXIf chunk whose length exceeds the file.
+	pngChunk := func(length uint32) []byte {
+		b := append([]byte(nil), pngMagic...)
+		b = binary.BigEndian.AppendUint32(b, length)
+		return append(b, "eXIfMM\x00\x2a"...)
+	}
+
+	tests := []struct {
+		name   string
+		source []byte
+	}{
+		{"png beyond end", pngChunk(1000)},
+		{"png beyond limit", pngChunk(0xffffffff)},
+		{"heic", heic[:len(heic)-1]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ReadEXIF(bytes.NewReader(tt.source)); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
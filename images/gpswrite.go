@@ -0,0 +1,439 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/bgraf/rueckblick/geotrack"
+	"github.com/bgraf/rueckblick/option"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported image format")
+
+// Additional tags of the GPS IFD written by WriteGPS.
+const (
+	tagGPSVersionID = 0x0000
+	tagGPSTimeStamp = 0x0007
+	tagGPSDateStamp = 0x001d
+)
+
+// byteOrder reads and appends integers of a TIFF structure.
+type byteOrder interface {
+	binary.ByteOrder
+	binary.AppendByteOrder
+}
+
+// Maximal payload of a JPEG marker segment.
+const maxJPEGSegmentPayload = 0xffff - 2
+
+// WriteGPSToFile stores the position, elevation and time of the point in the GPS tags of
+// a JPEG file. Existing GPS tags are replaced, all other metadata is kept.
+func WriteGPSToFile(path string, p geotrack.GPXPoint) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	b, err = WriteGPS(b, p)
+	if err != nil {
+		return err
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first, such that the photo is not lost on failure and
+	// files hard linked to it, e.g., copies in the build directory, are not changed.
+	f, err := os.CreateTemp(filepath.Dir(path), "tmp-rb.*"+filepath.Ext(path))
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(b)
+	err = errors.Join(err, f.Close())
+	if err == nil {
+		err = os.Chmod(f.Name(), fi.Mode())
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+
+	return nil
+}
+
+// Seconds of a coordinate are written in units of 1/10000.
+const dmsSecondsDenominator = 10000
+
+// toDMS splits a coordinate into degrees, minutes and seconds in units of 1/10000, where
+// rounding the seconds carries over into minutes and degrees.
+func toDMS(v float64) (degrees, minutes, seconds uint32) {
+	units := uint64(math.Round(math.Abs(v) * 3600 * dmsSecondsDenominator))
+
+	degrees = uint32(units / (3600 * dmsSecondsDenominator))
+	minutes = uint32(units / (60 * dmsSecondsDenominator) % 60)
+	seconds = uint32(units % (60 * dmsSecondsDenominator))
+
+	return degrees, minutes, seconds
+}
+
+// WriteGPS returns a copy of the JPEG image with the GPS tags set to the point.
+func WriteGPS(jpeg []byte, p geotrack.GPXPoint) ([]byte, error) {
+	if !bytes.HasPrefix(jpeg, jpegMagic) {
+		return nil, ErrUnsupportedFormat
+	}
+
+	start, end, found, err := findExifSegment(jpeg)
+	if err != nil {
+		return nil, err
+	}
+
+	var tiff []byte
+	if found {
+		tiff = jpeg[start+4+len(exifMagic) : end]
+	}
+
+	tiff, err = setGPSIFD(tiff, p)
+	if err != nil {
+		return nil, err
+	}
+
+	payloadLength := len(exifMagic) + len(tiff)
+	if payloadLength > maxJPEGSegmentPayload {
+		return nil, errors.New("EXIF data exceeds the size of a JPEG segment")
+	}
+
+	var buf bytes.Buffer
+	buf.Grow(len(jpeg) + payloadLength)
+
+	if !found {
+		// Insert the segment after the start of image and any JFIF segment.
+		start, end = 2, 2
+		if len(jpeg) >= 6 && jpeg[2] == 0xff && jpeg[3] == 0xe0 {
+			start = min(4+int(binary.BigEndian.Uint16(jpeg[4:6])), len(jpeg))
+			end = start
+		}
+	}
+
+	buf.Write(jpeg[:start])
+	buf.Write([]byte{0xff, 0xe1})
+	_ = binary.Write(&buf, binary.BigEndian, uint16(payloadLength+2))
+	buf.Write(exifMagic)
+	buf.Write(tiff)
+	buf.Write(jpeg[end:])
+
+	return buf.Bytes(), nil
+}
+
+// findExifSegment returns the bounds of the APP1 segment holding EXIF data, including its
+// marker and length.
+func findExifSegment(b []byte) (start, end int, found bool, err error) {
+	pos := 2
+
+	for pos+4 <= len(b) {
+		if b[pos] != 0xff {
+			return 0, 0, false, errors.New("invalid JPEG marker")
+		}
+
+		marker := b[pos+1]
+		switch {
+		case marker == 0xff:
+			// Fill byte
+			pos++
+			continue
+		case marker == 0xda || marker == 0xd9:
+			return 0, 0, false, nil
+		case marker == 0x01 || (marker >= 0xd0 && marker <= 0xd7):
+			pos += 2
+			continue
+		}
+
+		length := int(binary.BigEndian.Uint16(b[pos+2:]))
+		if length < 2 || pos+2+length > len(b) {
+			return 0, 0, false, errors.New("invalid JPEG segment length")
+		}
+
+		if marker == 0xe1 && bytes.HasPrefix(b[pos+4:], exifMagic) {
+			return pos, pos + 2 + length, true, nil
+		}
+
+		pos += 2 + length
+	}
+
+	return 0, 0, false, nil
+}
+
+// setGPSIFD returns the TIFF structure with a GPS IFD describing the point. An existing
+// GPS IFD is replaced in place if possible. Otherwise, IFD0 and the GPS IFD are appended
+// to the structure, such that the offsets of all other data, which may be referenced by
+// unknown maker notes, stay valid. A nil structure is replaced by a minimal one.
+func setGPSIFD(tiff []byte, p geotrack.GPXPoint) ([]byte, error) {
+	if tiff == nil {
+		tiff = []byte{'I', 'I', 42, 0, 8, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	}
+
+	if _, err := parseTIFF(tiff); err != nil {
+		return nil, fmt.Errorf("parse EXIF: %w", err)
+	}
+
+	var order byteOrder = binary.LittleEndian
+	if string(tiff[:2]) == "MM" {
+		order = binary.BigEndian
+	}
+
+	ifd0Offset := order.Uint32(tiff[4:8])
+	n := int(order.Uint16(tiff[ifd0Offset:]))
+	entriesStart := int(ifd0Offset) + 2
+
+	if entriesStart+12*n+4 > len(tiff) {
+		return nil, errInvalidTIFF
+	}
+
+	var entries [][]byte
+	for i := 0; i < n; i++ {
+		e := tiff[entriesStart+12*i : entriesStart+12*(i+1)]
+		if order.Uint16(e) != tagGPSIFDPointer {
+			entries = append(entries, e)
+			continue
+		}
+
+		if order.Uint16(e[2:]) == typeLong && order.Uint32(e[4:]) == 1 {
+			return replaceGPSIFD(tiff, order, entriesStart+12*i, p), nil
+		}
+	}
+
+	nextIFD := tiff[entriesStart+12*n : entriesStart+12*n+4]
+
+	out := slices.Clone(tiff)
+	if len(out)%2 != 0 {
+		out = append(out, 0)
+	}
+
+	newIFD0Offset := uint32(len(out))
+	gpsOffset := newIFD0Offset + uint32(2+12*(len(entries)+1)+4)
+
+	pointer := make([]byte, 12)
+	order.PutUint16(pointer[0:], tagGPSIFDPointer)
+	order.PutUint16(pointer[2:], typeLong)
+	order.PutUint32(pointer[4:], 1)
+	order.PutUint32(pointer[8:], gpsOffset)
+	entries = append(entries, pointer)
+
+	slices.SortFunc(entries, func(a, b []byte) int {
+		return int(order.Uint16(a)) - int(order.Uint16(b))
+	})
+
+	out = order.AppendUint16(out, uint16(len(entries)))
+	for _, e := range entries {
+		out = append(out, e...)
+	}
+	out = append(out, nextIFD...)
+
+	out = append(out, paddedGPSIFD(order, gpsOffset, p)...)
+
+	order.PutUint32(out[4:8], newIFD0Offset)
+
+	return out, nil
+}
+
+// replaceGPSIFD replaces the GPS IFD referenced by the IFD0 entry at the given position,
+// such that repeated writes do not grow the structure. The new GPS IFD overwrites the old
+// one if the old one ends the structure or if it fits. Otherwise, it is appended and the
+// entry is changed to point to it.
+func replaceGPSIFD(tiff []byte, order byteOrder, entryPos int, p geotrack.GPXPoint) []byte {
+	out := slices.Clone(tiff)
+	offset := order.Uint32(out[entryPos+8:])
+	end := gpsIFDEnd(out, order, offset)
+
+	ifd := paddedGPSIFD(order, offset, p)
+
+	switch {
+	case end >= len(out)-1:
+		// The old GPS IFD ends the structure, possibly followed by a padding byte.
+		out = append(out[:offset], ifd...)
+
+	case int(offset)+len(ifd) <= end:
+		copy(out[offset:], ifd)
+		clear(out[int(offset)+len(ifd) : end])
+
+	default:
+		if len(out)%2 != 0 {
+			out = append(out, 0)
+		}
+
+		offset = uint32(len(out))
+		out = append(out, paddedGPSIFD(order, offset, p)...)
+		order.PutUint32(out[entryPos+8:], offset)
+	}
+
+	return out
+}
+
+// gpsIFDEnd returns the end of the GPS IFD at the given offset including the values and
+// padding directly following it. Values stored elsewhere are left alone.
+func gpsIFDEnd(tiff []byte, order byteOrder, offset uint32) int {
+	n := int(order.Uint16(tiff[offset:]))
+	end := int(offset) + 2 + 12*n + 4
+
+	type value struct{ offset, size int }
+
+	var values []value
+	for i := 0; i < n; i++ {
+		e := tiff[int(offset)+2+12*i:]
+
+		size := typeSizes[order.Uint16(e[2:])] * int(order.Uint32(e[4:]))
+		if size > 4 {
+			values = append(values, value{int(order.Uint32(e[8:])), size})
+		}
+	}
+
+	slices.SortFunc(values, func(a, b value) int { return a.offset - b.offset })
+
+	for _, v := range values {
+		if (v.offset != end && v.offset != end+1) || v.offset+v.size > len(tiff) {
+			break
+		}
+
+		end = v.offset + v.size
+		if end%2 != 0 {
+			end++
+		}
+	}
+
+	end = min(end, len(tiff))
+
+	// Include the padding of GPS IFDs written by paddedGPSIFD.
+	padded := int(offset) + gpsIFDSize
+	if end < padded && padded <= len(tiff) && !slices.ContainsFunc(tiff[end:padded], func(b byte) bool { return b != 0 }) {
+		end = padded
+	}
+
+	return end
+}
+
+// gpsIFDSize is the size of a GPS IFD with all fields written by gpsIFD.
+var gpsIFDSize = len(gpsIFD(binary.LittleEndian, 0, geotrack.GPXPoint{
+	Elevation: option.Some(0.0),
+	Time:      time.Unix(0, 0),
+}))
+
+// paddedGPSIFD encodes the GPS IFD padded to gpsIFDSize, such that any later GPS IFD fits
+// into its place.
+func paddedGPSIFD(order byteOrder, offset uint32, p geotrack.GPXPoint) []byte {
+	ifd := gpsIFD(order, offset, p)
+	return append(ifd, make([]byte, gpsIFDSize-len(ifd))...)
+}
+
+// gpsIFD encodes the GPS IFD of the point located at the given offset.
+func gpsIFD(order byteOrder, offset uint32, p geotrack.GPXPoint) []byte {
+	type field struct {
+		tag   uint16
+		typ   uint16
+		count uint32
+		value []byte
+	}
+
+	ascii := func(s string) []byte { return append([]byte(s), 0) }
+
+	rationals := func(values ...[2]uint32) []byte {
+		var b []byte
+		for _, v := range values {
+			b = order.AppendUint32(b, v[0])
+			b = order.AppendUint32(b, v[1])
+		}
+		return b
+	}
+
+	dms := func(v float64) []byte {
+		degrees, minutes, seconds := toDMS(v)
+
+		return rationals(
+			[2]uint32{degrees, 1},
+			[2]uint32{minutes, 1},
+			[2]uint32{seconds, dmsSecondsDenominator},
+		)
+	}
+
+	latRef, lonRef := "N", "E"
+	if p.Lat < 0 {
+		latRef = "S"
+	}
+	if p.Lon < 0 {
+		lonRef = "W"
+	}
+
+	fields := []field{
+		{tagGPSVersionID, typeByte, 4, []byte{2, 3, 0, 0}},
+		{tagGPSLatitudeRef, typeASCII, 2, ascii(latRef)},
+		{tagGPSLatitude, typeRational, 3, dms(p.Lat)},
+		{tagGPSLongitudeRef, typeASCII, 2, ascii(lonRef)},
+		{tagGPSLongitude, typeRational, 3, dms(p.Lon)},
+	}
+
+	if p.Elevation.IsSome() {
+		elevation := p.Elevation.Get()
+
+		ref := byte(0)
+		if elevation < 0 {
+			ref = 1
+		}
+
+		fields = append(fields,
+			field{tagGPSAltitudeRef, typeByte, 1, []byte{ref}},
+			field{tagGPSAltitude, typeRational, 1, rationals([2]uint32{uint32(math.Round(math.Abs(elevation) * 100)), 100})},
+		)
+	}
+
+	if !p.Time.IsZero() {
+		t := p.Time.UTC().Truncate(time.Second)
+
+		fields = append(fields,
+			field{tagGPSTimeStamp, typeRational, 3, rationals(
+				[2]uint32{uint32(t.Hour()), 1},
+				[2]uint32{uint32(t.Minute()), 1},
+				[2]uint32{uint32(t.Second()), 1},
+			)},
+			field{tagGPSDateStamp, typeASCII, 11, ascii(t.Format("2006:01:02"))},
+		)
+	}
+
+	// Values longer than four bytes follow the IFD.
+	valuesOffset := offset + uint32(2+12*len(fields)+4)
+
+	var ifd, values []byte
+	ifd = order.AppendUint16(ifd, uint16(len(fields)))
+
+	for _, f := range fields {
+		ifd = order.AppendUint16(ifd, f.tag)
+		ifd = order.AppendUint16(ifd, f.typ)
+		ifd = order.AppendUint32(ifd, f.count)
+
+		if len(f.value) <= 4 {
+			ifd = append(ifd, f.value...)
+			ifd = append(ifd, make([]byte, 4-len(f.value))...)
+			continue
+		}
+
+		ifd = order.AppendUint32(ifd, valuesOffset+uint32(len(values)))
+		values = append(values, f.value...)
+		if len(values)%2 != 0 {
+			values = append(values, 0)
+		}
+	}
+
+	ifd = order.AppendUint32(ifd, 0)
+
+	return append(ifd, values...)
+}
@@ -0,0 +1,255 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image/jpeg"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bgraf/rueckblick/geotrack"
+	"github.com/bgraf/rueckblick/option"
+)
+
+func TestWriteGPS(t *testing.T) {
+	p := geotrack.GPXPoint{
+		Lat:       -(12.0 + 34.0/60 + 56.78/3600),
+		Lon:       98.765,
+		Time:      time.Date(2024, 7, 1, 9, 30, 15, 0, time.UTC),
+		Elevation: option.Some(1234.5),
+	}
+
+	tests := []struct {
+		file        string
+		wantModel   string
+		wantTime    bool
+		wantOrient  int
+		description string
+	}{
+		{file: "testdata/exif.jpg", wantModel: "ACME Camera 3000", wantTime: true, wantOrient: 6},
+		{file: "testdata/exif_bigendian.jpg", wantModel: "Big Endian Cam", wantTime: true, wantOrient: 1},
+		{file: "testdata/noexif.jpg"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			original, err := os.ReadFile(tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			b, err := WriteGPS(original, p)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := jpeg.Decode(bytes.NewReader(b)); err != nil {
+				t.Fatalf("image no longer decodes: %s", err)
+			}
+
+			data, err := ReadEXIF(bytes.NewReader(b))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if data.LatLon.IsNone() {
+				t.Fatal("no position written")
+			}
+
+			got := data.LatLon.Get()
+			if math.Abs(got.Lat-p.Lat) > 1e-7 || math.Abs(got.Lon-p.Lon) > 1e-7 {
+				t.Errorf("got position %v %v, want %v %v", got.Lat, got.Lon, p.Lat, p.Lon)
+			}
+
+			if data.Altitude.IsNone() || math.Abs(data.Altitude.Get()-1234.5) > 1e-9 {
+				t.Errorf("got altitude %v, want 1234.5", data.Altitude)
+			}
+
+			// Other metadata is kept.
+			if data.Model != tt.wantModel || data.Time.IsSome() != tt.wantTime || data.Orientation != tt.wantOrient {
+				t.Errorf("got model %q, time %v, orientation %d", data.Model, data.Time, data.Orientation)
+			}
+
+			// Writing again replaces the GPS tags.
+			q := p
+			q.Lat = 1
+			b, err = WriteGPS(b, q)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			data, err = ReadEXIF(bytes.NewReader(b))
+			if err != nil || data.LatLon.IsNone() || math.Abs(data.LatLon.Get().Lat-1) > 1e-7 {
+				t.Errorf("rewriting position failed: %v %v", data.LatLon, err)
+			}
+		})
+	}
+}
+
+func TestWriteGPSRepeatedly(t *testing.T) {
+	withElevation := geotrack.GPXPoint{Lat: 52.1, Lon: 8.2, Elevation: option.Some(80.0)}
+	withoutElevation := geotrack.GPXPoint{Lat: -33.5, Lon: 151.25}
+
+	for _, file := range []string{"testdata/exif.jpg", "testdata/exif_bigendian.jpg", "testdata/noexif.jpg"} {
+		t.Run(file, func(t *testing.T) {
+			b, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var sizes []int
+
+			// The GPS IFD with elevation is larger than the one without.
+			for _, p := range []geotrack.GPXPoint{withElevation, withElevation, withoutElevation, withElevation, withoutElevation} {
+				b, err = WriteGPS(b, p)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				data, err := ReadEXIF(bytes.NewReader(b))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				got := data.LatLon.Get()
+				if math.Abs(got.Lat-p.Lat) > 1e-7 || math.Abs(got.Lon-p.Lon) > 1e-7 || data.Altitude.IsSome() != p.Elevation.IsSome() {
+					t.Fatalf("got position %v %v, altitude %v, want %v %v, %v", got.Lat, got.Lon, data.Altitude, p.Lat, p.Lon, p.Elevation)
+				}
+
+				sizes = append(sizes, len(b))
+			}
+
+			// Only the first write adds to the EXIF data.
+			for i, size := range sizes[1:] {
+				if size != sizes[0] {
+					t.Errorf("write %d: got size %d, want %d", i+2, size, sizes[0])
+				}
+			}
+		})
+	}
+}
+
+func TestSetGPSIFDFollowedByData(t *testing.T) {
+	small := geotrack.GPXPoint{Lat: 1, Lon: 2}
+	medium := geotrack.GPXPoint{Lat: 3, Lon: 4, Elevation: option.Some(5.0)}
+	large := geotrack.GPXPoint{Lat: 6, Lon: 7, Elevation: option.Some(8.0), Time: time.Date(2024, 7, 1, 9, 30, 15, 0, time.UTC)}
+
+	var order byteOrder = binary.LittleEndian
+
+	variants := []struct {
+		name      string
+		gpsIFD    func(order byteOrder, offset uint32, p geotrack.GPXPoint) []byte
+		growFirst bool
+	}{
+		// Written before, thus replaced in place.
+		{"padded", paddedGPSIFD, false},
+		// Written by a camera just large enough for its fields, thus moved once.
+		{"camera", gpsIFD, true},
+	}
+
+	for _, variant := range variants {
+		t.Run(variant.name, func(t *testing.T) {
+			tiff := []byte{'I', 'I', 42, 0, 8, 0, 0, 0}
+			tiff = order.AppendUint16(tiff, 1)
+			tiff = order.AppendUint16(tiff, tagGPSIFDPointer)
+			tiff = order.AppendUint16(tiff, typeLong)
+			tiff = order.AppendUint32(tiff, 1)
+			tiff = order.AppendUint32(tiff, 26)
+			tiff = order.AppendUint32(tiff, 0)
+			tiff = append(tiff, variant.gpsIFD(order, 26, small)...)
+
+			// Other data after the GPS IFD must be kept.
+			trailer := []byte("trailing")
+			tiff = append(tiff, trailer...)
+
+			for i, p := range []geotrack.GPXPoint{small, large, medium, large, small} {
+				before := len(tiff)
+
+				var err error
+				tiff, err = setGPSIFD(tiff, p)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				wantGrow := i == 0 && variant.growFirst
+				if grown := len(tiff) > before; grown != wantGrow {
+					t.Errorf("write %d: size changed from %d to %d bytes", i+1, before, len(tiff))
+				}
+
+				if !bytes.Contains(tiff, trailer) {
+					t.Fatalf("write %d: overwrote the following data", i+1)
+				}
+
+				parsed, err := parseTIFF(tiff)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				got := parsed.exifData()
+				if got.LatLon.IsNone() || got.LatLon.Get().Lat != p.Lat || got.Altitude.IsSome() != p.Elevation.IsSome() {
+					t.Errorf("write %d: got %v, altitude %v, want %v", i+1, got.LatLon, got.Altitude, p)
+				}
+			}
+		})
+	}
+}
+
+func TestToDMS(t *testing.T) {
+	tests := []struct {
+		v                         float64
+		degrees, minutes, seconds uint32
+	}{
+		{12.0 + 34.0/60 + 56.78/3600, 12, 34, 567800},
+		{-98.765, 98, 45, 540000},
+		{52.99999999, 53, 0, 0},
+		{7.0 + 29.0/60 + 59.99999/3600, 7, 30, 0},
+		{0, 0, 0, 0},
+	}
+
+	for _, tt := range tests {
+		degrees, minutes, seconds := toDMS(tt.v)
+		if degrees != tt.degrees || minutes != tt.minutes || seconds != tt.seconds {
+			t.Errorf("toDMS(%v) = %d° %d' %d, want %d° %d' %d",
+				tt.v, degrees, minutes, seconds, tt.degrees, tt.minutes, tt.seconds)
+		}
+	}
+}
+
+func TestWriteGPSToFileKeepsHardLinks(t *testing.T) {
+	original, err := os.ReadFile("testdata/exif.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "photo.jpg")
+	link := filepath.Join(dir, "link.jpg")
+
+	if err := os.WriteFile(path, original, 0o640); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Link(path, link); err != nil {
+		t.Skip("hard links not supported:", err)
+	}
+
+	if err := WriteGPSToFile(path, geotrack.GPXPoint{Lat: 52, Lon: 8}); err != nil {
+		t.Fatal(err)
+	}
+
+	if linked, err := os.ReadFile(link); err != nil || !bytes.Equal(linked, original) {
+		t.Errorf("hard linked file was changed (%v)", err)
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil || fi.Mode().Perm() != 0o640 {
+		t.Errorf("got mode %v (%v), want 0640", fi.Mode(), err)
+	}
+
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("got %d files, want no temporary file left", len(entries))
+	}
+}
@@ -0,0 +1,143 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+
+	"github.com/disintegration/imaging"
+)
+
+type ScaleOptions struct {
+	MaxSize     int // Maximum width or height, zero keeps the original size
+	JPEGQuality int
+}
+
+// ScaleFile reads the image at `src`, rotates it according to its EXIF orientation, scales
+// it to fit into the maximum size, and writes it to `dst`. The output format is derived
+// from the extension of `dst`. JPEG outputs retain the EXIF data of JPEG inputs.
+func ScaleFile(src, dst string, opts ScaleOptions) error {
+	content, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return fmt.Errorf("decode '%s': %w", src, err)
+	}
+
+	var tiff []byte
+	var orientation int
+
+	if t, err := extractTIFF(bytes.NewReader(content)); err == nil {
+		if parsed, err := parseTIFF(t); err == nil {
+			tiff = t
+			orientation = parsed.exifData().Orientation
+		}
+	}
+
+	img = applyOrientation(img, orientation)
+
+	if opts.MaxSize > 0 {
+		bounds := img.Bounds()
+		if bounds.Dx() > opts.MaxSize || bounds.Dy() > opts.MaxSize {
+			img = imaging.Fit(img, opts.MaxSize, opts.MaxSize, imaging.Lanczos)
+		}
+	}
+
+	format, err := imaging.FormatFromFilename(dst)
+	if err != nil {
+		return fmt.Errorf("output '%s': %w", dst, err)
+	}
+
+	var buf bytes.Buffer
+	if err := imaging.Encode(&buf, img, format, imaging.JPEGQuality(opts.JPEGQuality)); err != nil {
+		return fmt.Errorf("encode '%s': %w", dst, err)
+	}
+
+	output := buf.Bytes()
+
+	if format == imaging.JPEG && tiff != nil {
+		if withExif, err := insertExifSegment(output, tiffWithOrientation(tiff, 1)); err == nil {
+			output = withExif
+		}
+	}
+
+	if err := os.WriteFile(dst, output, 0o666); err != nil {
+		return fmt.Errorf("write '%s': %w", filepath.Base(dst), err)
+	}
+
+	return nil
+}
+
+func applyOrientation(img image.Image, orientation int) image.Image {
+	switch orientation {
+	case 2:
+		return imaging.FlipH(img)
+	case 3:
+		return imaging.Rotate180(img)
+	case 4:
+		return imaging.FlipV(img)
+	case 5:
+		return imaging.Transpose(img)
+	case 6:
+		return imaging.Rotate270(img)
+	case 7:
+		return imaging.Transverse(img)
+	case 8:
+		return imaging.Rotate90(img)
+	}
+
+	return img
+}
+
+// tiffWithOrientation returns a copy of the TIFF structure with the orientation set to the
+// given value, so viewers do not rotate an already rotated image again.
+func tiffWithOrientation(tiff []byte, orientation uint16) []byte {
+	result := bytes.Clone(tiff)
+
+	t, err := parseTIFF(result)
+	if err != nil {
+		return result
+	}
+
+	// Values of at most four bytes are stored inline, thus the entry value refers into
+	// the copied buffer.
+	if e, ok := t.ifd0[tagOrientation]; ok && e.Type == typeShort && e.Count == 1 {
+		t.order.PutUint16(e.Value, orientation)
+	}
+
+	return result
+}
+
+var errExifTooLarge = errors.New("EXIF data exceeds JPEG segment size")
+
+// insertExifSegment inserts the TIFF structure as APP1 segment directly after the start of
+// image marker of the JPEG data. Existing EXIF segments are not removed.
+func insertExifSegment(jpegData []byte, tiff []byte) ([]byte, error) {
+	if !bytes.HasPrefix(jpegData, jpegMagic) {
+		return nil, fmt.Errorf("not a JPEG file")
+	}
+
+	length := 2 + len(exifMagic) + len(tiff)
+	if length > 0xffff {
+		return nil, errExifTooLarge
+	}
+
+	var buf bytes.Buffer
+	buf.Grow(len(jpegData) + length + 2)
+
+	buf.Write(jpegMagic)
+	buf.Write([]byte{0xff, 0xe1})
+	_ = binary.Write(&buf, binary.BigEndian, uint16(length))
+	buf.Write(exifMagic)
+	buf.Write(tiff)
+	buf.Write(jpegData[len(jpegMagic):])
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,45 @@
+package images
+
+import (
+	"image"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestScaleFile(t *testing.T) {
+	dst := filepath.Join(t.TempDir(), "scaled.jpg")
+
+	err := ScaleFile("testdata/exif.jpg", dst, ScaleOptions{MaxSize: 4, JPEGQuality: 90})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	f, err := os.Open(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+
+	cfg, _, err := image.DecodeConfig(f)
+	if err != nil {
+		t.Fatalf("decode scaled image: %s", err)
+	}
+
+	if cfg.Width > 4 || cfg.Height > 4 {
+		t.Errorf("image not scaled: %dx%d", cfg.Width, cfg.Height)
+	}
+
+	data, err := ReadEXIFFromFile(dst)
+	if err != nil {
+		t.Fatalf("EXIF not retained: %s", err)
+	}
+
+	if data.Orientation != 1 {
+		t.Errorf("orientation: got %d, want 1", data.Orientation)
+	}
+
+	if data.Model != "ACME Camera 3000" || data.LatLon.IsNone() {
+		t.Errorf("EXIF data lost: %+v", data)
+	}
+}
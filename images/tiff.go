@@ -0,0 +1,242 @@
+package images
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+var errInvalidTIFF = errors.New("invalid TIFF structure")
+
+// TIFF tags evaluated by the EXIF reader.
+const (
+	tagImageDescription   = 0x010e
+	tagMake               = 0x010f
+	tagModel              = 0x0110
+	tagOrientation        = 0x0112
+	tagExifIFDPointer     = 0x8769
+	tagGPSIFDPointer      = 0x8825
+	tagDateTimeOriginal   = 0x9003
+	tagOffsetTimeOriginal = 0x9011
+
+	tagGPSLatitudeRef  = 0x0001
+	tagGPSLatitude     = 0x0002
+	tagGPSLongitudeRef = 0x0003
+	tagGPSLongitude    = 0x0004
+	tagGPSAltitudeRef  = 0x0005
+	tagGPSAltitude     = 0x0006
+)
+
+// TIFF field types
+const (
+	typeByte      = 1
+	typeASCII     = 2
+	typeShort     = 3
+	typeLong      = 4
+	typeRational  = 5
+	typeUndefined = 7
+	typeSLong     = 9
+	typeSRational = 10
+)
+
+var typeSizes = map[uint16]int{
+	typeByte:      1,
+	typeASCII:     1,
+	typeShort:     2,
+	typeLong:      4,
+	typeRational:  8,
+	typeUndefined: 1,
+	typeSLong:     4,
+	typeSRational: 8,
+}
+
+type tiffEntry struct {
+	Type  uint16
+	Count uint32
+	Value []byte
+}
+
+type tiffIFD map[uint16]tiffEntry
+
+// tiffData holds the IFDs relevant for EXIF data of a TIFF structure.
+type tiffData struct {
+	order binary.ByteOrder
+	ifd0  tiffIFD
+	exif  tiffIFD
+	gps   tiffIFD
+}
+
+func parseTIFF(b []byte) (*tiffData, error) {
+	if len(b) < 8 {
+		return nil, errInvalidTIFF
+	}
+
+	var order binary.ByteOrder
+	switch string(b[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, errInvalidTIFF
+	}
+
+	if order.Uint16(b[2:4]) != 42 {
+		return nil, errInvalidTIFF
+	}
+
+	t := &tiffData{order: order}
+
+	var err error
+	t.ifd0, err = t.readIFD(b, order.Uint32(b[4:8]))
+	if err != nil {
+		return nil, fmt.Errorf("IFD0: %w", err)
+	}
+
+	if offset, ok := t.uint(t.ifd0, tagExifIFDPointer); ok {
+		if t.exif, err = t.readIFD(b, offset); err != nil {
+			return nil, fmt.Errorf("EXIF IFD: %w", err)
+		}
+	}
+
+	if offset, ok := t.uint(t.ifd0, tagGPSIFDPointer); ok {
+		if t.gps, err = t.readIFD(b, offset); err != nil {
+			return nil, fmt.Errorf("GPS IFD: %w", err)
+		}
+	}
+
+	return t, nil
+}
+
+func (t *tiffData) readIFD(b []byte, offset uint32) (tiffIFD, error) {
+	if uint64(offset)+2 > uint64(len(b)) {
+		return nil, errInvalidTIFF
+	}
+
+	n := int(t.order.Uint16(b[offset:]))
+	pos := int(offset) + 2
+
+	if pos+12*n > len(b) {
+		return nil, errInvalidTIFF
+	}
+
+	ifd := make(tiffIFD, n)
+
+	for i := 0; i < n; i++ {
+		e := b[pos+12*i : pos+12*(i+1)]
+
+		tag := t.order.Uint16(e[0:2])
+		typ := t.order.Uint16(e[2:4])
+		count := t.order.Uint32(e[4:8])
+
+		size, ok := typeSizes[typ]
+		if !ok {
+			// Unknown types are skipped, as required by the specification.
+			continue
+		}
+
+		total := uint64(size) * uint64(count)
+
+		var value []byte
+		if total <= 4 {
+			value = e[8 : 8+total]
+		} else {
+			valueOffset := uint64(t.order.Uint32(e[8:12]))
+			if valueOffset+total > uint64(len(b)) {
+				continue
+			}
+			value = b[valueOffset : valueOffset+total]
+		}
+
+		ifd[tag] = tiffEntry{Type: typ, Count: count, Value: value}
+	}
+
+	return ifd, nil
+}
+
+func (t *tiffData) uint(ifd tiffIFD, tag uint16) (uint32, bool) {
+	e, ok := ifd[tag]
+	if !ok || e.Count == 0 {
+		return 0, false
+	}
+
+	switch e.Type {
+	case typeByte, typeUndefined:
+		return uint32(e.Value[0]), true
+	case typeShort:
+		return uint32(t.order.Uint16(e.Value)), true
+	case typeLong, typeSLong:
+		return t.order.Uint32(e.Value), true
+	}
+
+	return 0, false
+}
+
+func (t *tiffData) string(ifd tiffIFD, tag uint16) (string, bool) {
+	e, ok := ifd[tag]
+	if !ok || (e.Type != typeASCII && e.Type != typeUndefined) {
+		return "", false
+	}
+
+	s := string(e.Value)
+	if i := strings.IndexByte(s, 0); i >= 0 {
+		s = s[:i]
+	}
+
+	s = strings.TrimSpace(s)
+
+	return s, len(s) > 0
+}
+
+func (t *tiffData) rationals(ifd tiffIFD, tag uint16) ([]float64, bool) {
+	e, ok := ifd[tag]
+	if !ok || (e.Type != typeRational && e.Type != typeSRational) {
+		return nil, false
+	}
+
+	values := make([]float64, e.Count)
+	for i := range values {
+		num := t.order.Uint32(e.Value[8*i:])
+		den := t.order.Uint32(e.Value[8*i+4:])
+
+		if den == 0 {
+			return nil, false
+		}
+
+		if e.Type == typeSRational {
+			values[i] = float64(int32(num)) / float64(int32(den))
+		} else {
+			values[i] = float64(num) / float64(den)
+		}
+	}
+
+	return values, true
+}
+
+// coordinate reads a GPS coordinate given as degrees, minutes, and seconds.
+func (t *tiffData) coordinate(valueTag, refTag uint16, negativeRef string) (float64, bool) {
+	dms, ok := t.rationals(t.gps, valueTag)
+	if !ok || len(dms) == 0 {
+		return 0, false
+	}
+
+	v := dms[0]
+	if len(dms) > 1 {
+		v += dms[1] / 60
+	}
+	if len(dms) > 2 {
+		v += dms[2] / 3600
+	}
+
+	if ref, ok := t.string(t.gps, refTag); ok && strings.EqualFold(ref, negativeRef) {
+		v = -v
+	}
+
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, false
+	}
+
+	return v, true
+}
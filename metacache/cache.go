@@ -0,0 +1,266 @@
+package metacache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/bgraf/rueckblick/data"
+	"github.com/bgraf/rueckblick/geotrack"
+	"github.com/bgraf/rueckblick/images"
+	"github.com/bgraf/rueckblick/option"
+)
+
+// Name of the cache file within the build directory.
+const FileName = "metadata.json"
+
+// Cache maps file paths to metadata extracted from the files. Entries are invalidated when
+// the size or modification time of a file changes. A cache is safe for concurrent use.
+type Cache struct {
+	path string
+
+	mu      sync.Mutex
+	entries map[string]*entry
+	dirty   bool
+}
+
+type entry struct {
+	Size    int64         `json:"size"`
+	ModTime time.Time     `json:"modTime"`
+	EXIF    *exifRecord   `json:"exif,omitempty"`
+	Track   *TrackSummary `json:"track,omitempty"`
+}
+
+type exifRecord struct {
+	Missing     bool           `json:"missing,omitempty"` // The file has no EXIF data
+	Time        *time.Time     `json:"time,omitempty"`
+	TimeOffset  *time.Duration `json:"timeOffset,omitempty"`
+	Lat         *float64       `json:"lat,omitempty"`
+	Lon         *float64       `json:"lon,omitempty"`
+	Altitude    *float64       `json:"altitude,omitempty"`
+	Orientation int            `json:"orientation,omitempty"`
+	Make        string         `json:"make,omitempty"`
+	Model       string         `json:"model,omitempty"`
+	Description string         `json:"description,omitempty"`
+}
+
+// Open reads the cache from the given build directory. A missing or unreadable cache
+// file results in an empty cache.
+func Open(buildDirectory string) *Cache {
+	c := &Cache{
+		path:    filepath.Join(buildDirectory, FileName),
+		entries: make(map[string]*entry),
+	}
+
+	payloadBytes, err := os.ReadFile(c.path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("could not read metadata cache: %s", err)
+		}
+		return c
+	}
+
+	if err := json.Unmarshal(payloadBytes, &c.entries); err != nil {
+		log.Printf("could not parse metadata cache: %s", err)
+		c.entries = make(map[string]*entry)
+	}
+
+	return c
+}
+
+// Save writes the cache file, if the cache changed. Entries of removed files are dropped.
+func (c *Cache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for path := range c.entries {
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			delete(c.entries, path)
+			c.dirty = true
+		}
+	}
+
+	if !c.dirty {
+		return nil
+	}
+
+	payloadBytes, err := json.Marshal(c.entries)
+	if err != nil {
+		return fmt.Errorf("could not serialize metadata cache: %w", err)
+	}
+
+	if err := os.WriteFile(c.path, payloadBytes, 0o666); err != nil {
+		return fmt.Errorf("could not write metadata cache: %w", err)
+	}
+
+	c.dirty = false
+
+	return nil
+}
+
+// lookup returns the valid entry of the file, creating an empty one if the file changed.
+// Must be called with the mutex held.
+func (c *Cache) lookup(path string, fi os.FileInfo) *entry {
+	e, ok := c.entries[path]
+	if ok && e.Size == fi.Size() && e.ModTime.Equal(fi.ModTime()) {
+		return e
+	}
+
+	e = &entry{Size: fi.Size(), ModTime: fi.ModTime()}
+	c.entries[path] = e
+
+	return e
+}
+
+// EXIF returns the EXIF data of the image file, reading it only if the file changed since
+// it was cached.
+func (c *Cache) EXIF(path string) (images.EXIFData, error) {
+	path, fi, err := stat(path)
+	if err != nil {
+		return images.EXIFData{}, err
+	}
+
+	c.mu.Lock()
+	if e := c.lookup(path, fi); e.EXIF != nil {
+		c.mu.Unlock()
+		return e.EXIF.exifData()
+	}
+	c.mu.Unlock()
+
+	exif, err := images.ReadEXIFFromFile(path)
+	if err != nil && !errors.Is(err, images.ErrNoExif) {
+		return exif, err
+	}
+
+	c.mu.Lock()
+	c.lookup(path, fi).EXIF = newEXIFRecord(exif, err != nil)
+	c.dirty = true
+	c.mu.Unlock()
+
+	return exif, err
+}
+
+// TrackSummary returns the summary of the track file, loading the track only if the file
+// changed since it was cached.
+func (c *Cache) TrackSummary(path string) (TrackSummary, error) {
+	path, fi, err := stat(path)
+	if err != nil {
+		return TrackSummary{}, err
+	}
+
+	c.mu.Lock()
+	if e := c.lookup(path, fi); e.Track != nil && e.Track.Version == summaryVersion {
+		c.mu.Unlock()
+		return *e.Track, nil
+	}
+	c.mu.Unlock()
+
+	collection, err := data.LoadTrackCollection(path)
+	if err != nil {
+		return TrackSummary{}, err
+	}
+
+	summary := Summarize(collection)
+
+	c.mu.Lock()
+	c.lookup(path, fi).Track = &summary
+	c.dirty = true
+	c.mu.Unlock()
+
+	return summary, nil
+}
+
+func stat(path string) (string, os.FileInfo, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return path, nil, err
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return path, nil, err
+	}
+
+	return path, fi, nil
+}
+
+func newEXIFRecord(exif images.EXIFData, missing bool) *exifRecord {
+	r := &exifRecord{
+		Missing:     missing,
+		Orientation: exif.Orientation,
+		Make:        exif.Make,
+		Model:       exif.Model,
+		Description: exif.Description,
+	}
+
+	if exif.Time.IsSome() {
+		t := exif.Time.Get()
+		r.Time = &t
+	}
+
+	if exif.TimeOffset.IsSome() {
+		offset := exif.TimeOffset.Get()
+		r.TimeOffset = &offset
+	}
+
+	if exif.LatLon.IsSome() {
+		p := exif.LatLon.Get()
+		r.Lat, r.Lon = &p.Lat, &p.Lon
+	}
+
+	if exif.Altitude.IsSome() {
+		alt := exif.Altitude.Get()
+		r.Altitude = &alt
+	}
+
+	return r
+}
+
+func (r *exifRecord) exifData() (images.EXIFData, error) {
+	exif := images.EXIFData{
+		Time:        option.None[time.Time](),
+		TimeOffset:  option.None[time.Duration](),
+		LatLon:      option.None[geotrack.GPXPoint](),
+		Altitude:    option.None[float64](),
+		Orientation: r.Orientation,
+		Make:        r.Make,
+		Model:       r.Model,
+		Description: r.Description,
+	}
+
+	if r.Missing {
+		return exif, images.ErrNoExif
+	}
+
+	if r.TimeOffset != nil {
+		exif.TimeOffset = option.Some(*r.TimeOffset)
+	}
+
+	if r.Time != nil {
+		t := *r.Time
+		if r.TimeOffset == nil {
+			// Times without recorded offset are interpreted in the local time zone.
+			t = t.In(time.Local)
+		}
+		exif.Time = option.Some(t)
+	}
+
+	if r.Lat != nil && r.Lon != nil {
+		p := geotrack.GPXPoint{Lat: *r.Lat, Lon: *r.Lon}
+		if exif.Time.IsSome() {
+			p.Time = exif.Time.Get()
+		}
+		exif.LatLon = option.Some(p)
+	}
+
+	if r.Altitude != nil {
+		exif.Altitude = option.Some(*r.Altitude)
+	}
+
+	return exif, nil
+}
@@ -0,0 +1,121 @@
+package metacache
+
+import (
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bgraf/rueckblick/geotrack"
+	"github.com/bgraf/rueckblick/images"
+)
+
+func TestEXIFRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, name := range []string{"exif.jpg", "exif_bigendian.jpg"} {
+		src := filepath.Join("..", "images", "testdata", name)
+
+		want, err := images.ReadEXIFFromFile(src)
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+
+		c := Open(dir)
+		if _, err := c.EXIF(src); err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		if err := c.Save(); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := Open(dir).EXIF(src)
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+
+		if !got.Time.Get().Equal(want.Time.Get()) {
+			t.Errorf("%s: time %v, want %v", name, got.Time.Get(), want.Time.Get())
+		}
+		if got.TimeOffset != want.TimeOffset {
+			t.Errorf("%s: offset %v, want %v", name, got.TimeOffset, want.TimeOffset)
+		}
+		if got.LatLon.Get().Lat != want.LatLon.Get().Lat || got.LatLon.Get().Lon != want.LatLon.Get().Lon {
+			t.Errorf("%s: position %v, want %v", name, got.LatLon.Get(), want.LatLon.Get())
+		}
+		if got.Altitude != want.Altitude || got.Orientation != want.Orientation || got.Model != want.Model {
+			t.Errorf("%s: got %+v, want %+v", name, got, want)
+		}
+	}
+}
+
+func TestEXIFMissing(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join("..", "images", "testdata", "noexif.jpg")
+
+	c := Open(dir)
+	if _, err := c.EXIF(src); !errors.Is(err, images.ErrNoExif) {
+		t.Fatalf("got %v, want ErrNoExif", err)
+	}
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, FileName)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Open(dir).EXIF(src); !errors.Is(err, images.ErrNoExif) {
+		t.Fatalf("got %v from cache, want ErrNoExif", err)
+	}
+}
+
+func TestFarthestFrom(t *testing.T) {
+	var points []geotrack.GPXPoint
+	for i := 0; i <= 10; i++ {
+		for j := 0; j <= 10; j++ {
+			points = append(points, geotrack.GPXPoint{Lat: 50 + float64(i)/10, Lon: 8 + float64(j)/10})
+		}
+	}
+
+	s := Summarize(geotrack.NewSinglePathCollection(points))
+	if len(s.Hull) != 4 {
+		t.Errorf("hull has %d vertices, want 4", len(s.Hull))
+	}
+
+	p, _ := s.FarthestFrom(49, 7)
+	if p.Lat != 51 || p.Lon != 9 {
+		t.Errorf("farthest point %v, want [51 9]", p)
+	}
+}
+
+func TestSummarizePathAndCells(t *testing.T) {
+	points := []geotrack.GPXPoint{
+		{Lat: 52.0001, Lon: 8.0001},
+		{Lat: 52.0002, Lon: 8.0002},
+		{Lat: 52.0003, Lon: 8.0003},
+		{Lat: 52.0101, Lon: 8.0001},
+	}
+
+	s := Summarize(geotrack.NewSinglePathCollection(points))
+
+	// The second point lies on the line between its neighbours.
+	if len(s.Path) != 1 || len(s.Path[0]) != 3 {
+		t.Errorf("path %v, want one segment of 3 points", s.Path)
+	}
+
+	want := [][3]float64{{52.0025, 8.0025, 3}, {52.0125, 8.0025, 1}}
+	if len(s.Cells) != len(want) {
+		t.Fatalf("cells %v, want %v", s.Cells, want)
+	}
+
+	for i := range want {
+		for j := range want[i] {
+			if math.Abs(s.Cells[i][j]-want[i][j]) > 1e-9 {
+				t.Errorf("cell %d is %v, want %v", i, s.Cells[i], want[i])
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,3 @@
+// Package metacache persists metadata extracted from journal files, such as EXIF data of
+// images and summaries of tracks, so it need not be extracted again on every build.
+package metacache
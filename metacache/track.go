@@ -0,0 +1,169 @@
+package metacache
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/bgraf/rueckblick/geotrack"
+	"github.com/jftuga/geodist"
+)
+
+// Version of the summary format. Cached summaries of other versions are recomputed.
+const summaryVersion = 2
+
+// Tolerance in meters of the simplified path of a summary.
+const pathTolerance = 5.0
+
+// Edge length in degrees of the grid cells counting the points of a track.
+const cellSize = 0.005
+
+// TrackSummary describes a track without keeping all of its points.
+type TrackSummary struct {
+	Version int       `json:"version"`
+	Points  int       `json:"points"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	MinLat  float64   `json:"minLat"`
+	MinLon  float64   `json:"minLon"`
+	MaxLat  float64   `json:"maxLat"`
+	MaxLon  float64   `json:"maxLon"`
+
+	// Convex hull of the track's points as latitude, longitude pairs. The point of a track
+	// farthest from any location is a vertex of the hull.
+	Hull [][2]float64 `json:"hull"`
+
+	// Simplified segments of the track as latitude, longitude pairs.
+	Path [][][2]float64 `json:"path"`
+
+	// Number of points within grid cells as latitude and longitude of the cell's center
+	// and count.
+	Cells [][3]float64 `json:"cells"`
+}
+
+// Summarize computes the summary of the track collection's points.
+func Summarize(c *geotrack.Collection) TrackSummary {
+	points := c.Points()
+
+	s := TrackSummary{Version: summaryVersion, Points: len(points)}
+	if len(points) == 0 {
+		return s
+	}
+
+	s.Start, s.End = points[0].Time, points[len(points)-1].Time
+	s.MinLat, s.MaxLat = points[0].Lat, points[0].Lat
+	s.MinLon, s.MaxLon = points[0].Lon, points[0].Lon
+
+	coords := make([][2]float64, len(points))
+	for i, p := range points {
+		s.MinLat = min(s.MinLat, p.Lat)
+		s.MaxLat = max(s.MaxLat, p.Lat)
+		s.MinLon = min(s.MinLon, p.Lon)
+		s.MaxLon = max(s.MaxLon, p.Lon)
+		coords[i] = [2]float64{p.Lat, p.Lon}
+	}
+
+	s.Hull = convexHull(coords)
+
+	for _, segment := range c.Segments() {
+		simplified := geotrack.Simplify(segment.Points, pathTolerance)
+
+		path := make([][2]float64, len(simplified))
+		for i, p := range simplified {
+			path[i] = [2]float64{p.Lat, p.Lon}
+		}
+
+		s.Path = append(s.Path, path)
+	}
+
+	s.Cells = countCells(points)
+
+	return s
+}
+
+// countCells counts the points within the cells of a grid.
+func countCells(points []geotrack.GPXPoint) [][3]float64 {
+	type cell struct{ lat, lon int }
+
+	counts := make(map[cell]int)
+	var order []cell
+
+	for _, p := range points {
+		k := cell{int(math.Floor(p.Lat / cellSize)), int(math.Floor(p.Lon / cellSize))}
+		if counts[k] == 0 {
+			order = append(order, k)
+		}
+		counts[k]++
+	}
+
+	cells := make([][3]float64, len(order))
+	for i, k := range order {
+		// Rounding avoids long decimal representations of the centers.
+		cells[i] = [3]float64{
+			math.Round((float64(k.lat)+0.5)*cellSize*1e4) / 1e4,
+			math.Round((float64(k.lon)+0.5)*cellSize*1e4) / 1e4,
+			float64(counts[k]),
+		}
+	}
+
+	return cells
+}
+
+// FarthestFrom returns the point of the track farthest from the given coordinates and its
+// distance in kilometers.
+func (s TrackSummary) FarthestFrom(lat, lon float64) (geotrack.GPXPoint, float64) {
+	from := geodist.Coord{Lat: lat, Lon: lon}
+
+	maxDist := 0.0
+	var maxPoint geotrack.GPXPoint
+
+	for _, p := range s.Hull {
+		_, dkm := geodist.HaversineDistance(from, geodist.Coord{Lat: p[0], Lon: p[1]})
+		if dkm > maxDist {
+			maxDist = dkm
+			maxPoint = geotrack.GPXPoint{Lat: p[0], Lon: p[1]}
+		}
+	}
+
+	return maxPoint, maxDist
+}
+
+// convexHull computes the convex hull using Andrew's monotone chain algorithm.
+func convexHull(points [][2]float64) [][2]float64 {
+	sort.Slice(points, func(i, j int) bool {
+		if points[i][0] != points[j][0] {
+			return points[i][0] < points[j][0]
+		}
+		return points[i][1] < points[j][1]
+	})
+
+	if len(points) < 3 {
+		return points
+	}
+
+	cross := func(o, a, b [2]float64) float64 {
+		return (a[0]-o[0])*(b[1]-o[1]) - (a[1]-o[1])*(b[0]-o[0])
+	}
+
+	hull := make([][2]float64, 0, 2*len(points))
+
+	// Lower hull
+	for _, p := range points {
+		for len(hull) >= 2 && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+
+	// Upper hull
+	lower := len(hull) + 1
+	for i := len(points) - 2; i >= 0; i-- {
+		p := points[i]
+		for len(hull) >= lower && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+
+	return hull[:len(hull)-1]
+}
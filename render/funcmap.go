@@ -6,6 +6,7 @@ import (
 	"net/url"
 	"time"
 
+	"github.com/bgraf/rueckblick/config"
 	"github.com/bgraf/rueckblick/data"
 	"github.com/bgraf/rueckblick/util/dates"
 	"github.com/goodsign/monday"
@@ -14,21 +15,22 @@ import (
 func makeTemplateFuncmap() template.FuncMap {
 	tagSet := NewTagSet()
 
+	icons := map[string]string{"period": "icon-period"}
+	for _, category := range config.TagCategories() {
+		icons[category.Key] = category.Icon
+	}
+
 	return template.FuncMap{
 		"tagColor": func(tag data.Tag) string {
 			return tagSet.HexColor(tag.String())
 		},
 		"tagDisplay": func(tag data.Tag) template.HTML {
-			switch tag.Category {
-			case "location":
-				return template.HTML(fmt.Sprintf("<i class=\"icon-map-pin-line icon-small\"></i> %s", tag.String()))
-			case "people":
-				return template.HTML(fmt.Sprintf("<i class=\"icon-user icon-small\"></i> %s", tag.String()))
-			case "period":
-				return template.HTML(fmt.Sprintf("<i class=\"icon-period icon-small\"></i> %s", tag.String()))
-			default:
-				return template.HTML(tag.String())
+			name := template.HTMLEscapeString(tag.String())
+			if icon := icons[tag.Category]; icon != "" {
+				return template.HTML(fmt.Sprintf("<i class=\"%s icon-small\"></i> %s", template.HTMLEscapeString(icon), name))
 			}
+
+			return template.HTML(name)
 		},
 		"isFirstOfWeek": func(t time.Time) bool {
 			return t.Weekday() == time.Monday
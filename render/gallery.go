@@ -2,6 +2,7 @@ package render
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -25,14 +26,23 @@ const GalleryTagDirectoryAttrName = "directory"
 // Name of the attribute to specify the include pattern for file names
 const GalleryTagIncludeAttrName = "include"
 
+// Names of the attributes to specify the time zone and offset of the camera clock, which
+// override those of the document
+const (
+	GalleryTagTimezoneAttrName    = "timezone"
+	GalleryTagClockOffsetAttrName = "clock-offset"
+)
+
 type MapToResourceFunc func(original string) (data.Resource, bool)
 
 // EmplaceGalleries replaces each `<rb-gallery ... />` node with a collection of nodes representing
-// an actual gallery in HTML code.
-func EmplaceGalleries(doc *data.Document, toResource MapToResourceFunc) {
+// an actual gallery in HTML code. The EXIF data of the images is obtained via readEXIF.
+func EmplaceGalleries(doc *data.Document, toResource MapToResourceFunc, readEXIF func(path string) (images.EXIFData, error)) error {
 	galleryID := -1
 
-	doc.HTML.Find(GalleryTagName).Each(func(i int, s *goquery.Selection) {
+	var err error
+
+	doc.HTML.Find(GalleryTagName).EachWithBreak(func(i int, s *goquery.Selection) bool {
 		galleryID++
 
 		photoDir := s.AttrOr(GalleryTagDirectoryAttrName, config.DefaultPhotosDirectory())
@@ -42,10 +52,18 @@ func EmplaceGalleries(doc *data.Document, toResource MapToResourceFunc) {
 
 		pat := s.AttrOr(GalleryTagIncludeAttrName, "*.*")
 
-		files, err := collectGalleryImagePaths(photoDir, pat)
+		var clock data.CameraClock
+		clock, err = doc.Clock.With(s.AttrOr(GalleryTagTimezoneAttrName, ""), s.AttrOr(GalleryTagClockOffsetAttrName, ""))
 		if err != nil {
-			log.Printf("error while collecting gallery images: %s", err)
-			return
+			err = fmt.Errorf("gallery %d: %w", galleryID, err)
+			return false
+		}
+
+		var files []string
+		files, err = collectGalleryImagePaths(photoDir, pat)
+		if err != nil {
+			err = fmt.Errorf("could not collect gallery images: %w", err)
+			return false
 		}
 
 		filesExif := make(
@@ -61,12 +79,10 @@ func EmplaceGalleries(doc *data.Document, toResource MapToResourceFunc) {
 
 			var err error
 
-			filesExif[i].exif, err = images.ReadEXIFFromFile(filePath)
-			if err != nil {
+			filesExif[i].exif, err = readEXIF(filePath)
+			if err != nil && !errors.Is(err, images.ErrNoExif) {
 				log.Printf("EXIF failed: %v => %s\n", filePath, err)
-
 			}
-			_ = err // Note: check `err` to see whether EXIF reading succeeded
 		}
 
 		sort.Slice(
@@ -95,6 +111,7 @@ func EmplaceGalleries(doc *data.Document, toResource MapToResourceFunc) {
 		galleryElementID := fmt.Sprintf("gallery-%d", galleryID)
 		gallery := &data.Gallery{
 			ElementID: galleryElementID,
+			Clock:     clock,
 		}
 		doc.Galleries = append(doc.Galleries, gallery)
 
@@ -122,7 +139,7 @@ func EmplaceGalleries(doc *data.Document, toResource MapToResourceFunc) {
 
 			resPath := resource.URI
 
-			gallery.AppendImage(resource, thumbRes, file.path, file.exif.Time, file.exif.LatLon)
+			gallery.AppendImage(resource, thumbRes, file.path, file.exif.Time, file.exif.TimeOffset, file.exif.LatLon)
 
 			buf.WriteString("<div class=\"gallery-entry\"><a href=\"")
 			buf.WriteString(resPath)
@@ -150,7 +167,11 @@ func EmplaceGalleries(doc *data.Document, toResource MapToResourceFunc) {
 		))
 
 		s.ReplaceWithHtml(buf.String())
+
+		return true
 	})
+
+	return err
 }
 
 func collectGalleryImagePaths(directory string, pattern string) ([]string, error) {
@@ -9,6 +9,7 @@ import (
 	"github.com/PuerkitoBio/goquery"
 	"github.com/bgraf/rueckblick/config"
 	"github.com/bgraf/rueckblick/data"
+	"github.com/bgraf/rueckblick/geotrack"
 )
 
 // Name of a markdown document tag for GPX tracks
@@ -22,9 +23,12 @@ const GPXTagTrackAtteName = "track"
 //
 // Note: requires that the `doc.Galleries` are populated, otherwise matching of images to
 // locations will yield no results.
-func EmplaceGPXMaps(doc *data.Document, toResource MapToResourceFunc) {
+func EmplaceGPXMaps(doc *data.Document, toResource MapToResourceFunc) error {
 	mapID := -1
-	doc.HTML.Find(GPXTagName).Each(func(i int, s *goquery.Selection) {
+
+	var err error
+
+	doc.HTML.Find(GPXTagName).EachWithBreak(func(i int, s *goquery.Selection) bool {
 		mapID++
 
 		trackFile := s.AttrOr(GPXTagTrackAtteName, config.DefaultGPXFile())
@@ -32,24 +36,34 @@ func EmplaceGPXMaps(doc *data.Document, toResource MapToResourceFunc) {
 			trackFile = path.Join(doc.DocumentDirectory(), trackFile)
 		}
 
-		points, images, err := data.LoadTrackWithImages(doc, trackFile)
+		var (
+			collection *geotrack.Collection
+			images     []data.GPXLocatedImage
+		)
+
+		collection, images, err = data.LoadTrackWithImages(doc, trackFile)
 		if err != nil {
-			panic(err)
+			err = fmt.Errorf("could not load track '%s': %w", trackFile, err)
+			return false
 		}
 
 		// Build json payload
-		payload := map[string]any{
-			"track":  points,
-			"images": images,
-		}
-		payloadBytes, err := json.Marshal(payload)
+		payload := makeTrackPayload(collection)
+		payload["images"] = images
+
+		var payloadBytes []byte
+		payloadBytes, err = json.Marshal(payload)
 		if err != nil {
-			panic(err)
+			return false
 		}
 
+		// Installs the track file alongside the rendered document.
+		resource, _ := toResource(trackFile)
+
 		mapElementID := fmt.Sprintf("map-%d", mapID)
 		doc.Maps = append(doc.Maps, data.GXPMap{
 			GPXPath:   trackFile,
+			Resource:  resource,
 			ElementID: mapElementID,
 		})
 
@@ -72,9 +86,54 @@ func EmplaceGPXMaps(doc *data.Document, toResource MapToResourceFunc) {
 			payloadStr,
 		))
 		_, _ = buf.WriteString("</div>")
+		_, _ = buf.WriteString(trackSummaryHTML(collection))
 
 		s.ReplaceWithHtml(buf.String())
+
+		return true
 	})
+
+	return err
+}
+
+// makeTrackPayload converts the collection into the map data expected by `mountMap`. Each
+// track is a list of segments, which are drawn as separate lines.
+func makeTrackPayload(c *geotrack.Collection) map[string]any {
+	type trackPayload struct {
+		Name     string                `json:"name"`
+		Segments [][]geotrack.GPXPoint `json:"segments"`
+	}
+
+	type waypointPayload struct {
+		LatLng      geotrack.GPXPoint
+		Name        string
+		Description string
+	}
+
+	var tracks []trackPayload
+	for _, track := range c.Tracks {
+		t := trackPayload{Name: track.Name}
+		for _, segment := range track.Segments {
+			t.Segments = append(t.Segments, segment.Points)
+		}
+		tracks = append(tracks, t)
+	}
+
+	var routes []trackPayload
+	for _, route := range c.Routes {
+		routes = append(routes, trackPayload{Name: route.Name, Segments: [][]geotrack.GPXPoint{route.Points}})
+	}
+
+	var waypoints []waypointPayload
+	for _, w := range c.Waypoints {
+		waypoints = append(waypoints, waypointPayload{LatLng: w.Point, Name: w.Name, Description: w.Description})
+	}
+
+	return map[string]any{
+		"tracks":    tracks,
+		"routes":    routes,
+		"waypoints": waypoints,
+	}
 }
 
 // GeoMaps finds all track files embedded in the document.
@@ -101,7 +160,7 @@ func GeoMaps(doc *data.Document) []data.GXPMap {
 }
 
 // InsertTracklessMap checks for geo-images and inserts them into a map above the first gallery.
-func InsertTracklessMap(doc *data.Document) {
+func InsertTracklessMap(doc *data.Document) error {
 	var images []data.GPXLocatedImage
 	for _, gallery := range doc.Galleries {
 		for _, img := range gallery.Images {
@@ -120,7 +179,7 @@ func InsertTracklessMap(doc *data.Document) {
 		}
 		payloadBytes, err := json.Marshal(payload)
 		if err != nil {
-			panic(err)
+			return err
 		}
 
 		payloadStr := string(payloadBytes)
@@ -144,4 +203,6 @@ func InsertTracklessMap(doc *data.Document) {
 		_, _ = buf.WriteString("</div>")
 		doc.HTML.Find("div.gallery").First().BeforeHtml(buf.String())
 	}
+
+	return nil
 }
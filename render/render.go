@@ -4,10 +4,12 @@ import (
 	"path/filepath"
 
 	"github.com/bgraf/rueckblick/data"
+	"github.com/bgraf/rueckblick/images"
 )
 
-// postprocessDocument modifies the rendered document by replacing links, image and video sources.
-func Render(doc *data.Document, opts data.StoreOptions) {
+// Render modifies the rendered document by replacing links, image and video sources. Errors
+// are of type *data.DocumentError naming the failing stage.
+func Render(doc *data.Document, opts data.StoreOptions) error {
 	ImplicitFigure(doc)
 
 	toResource := func(original string) (data.Resource, bool) {
@@ -15,21 +17,36 @@ func Render(doc *data.Document, opts data.StoreOptions) {
 		if !filepath.IsAbs(original) {
 			srcPath = filepath.Join(filepath.Dir(doc.Path), original)
 		}
-		resource, _ := opts.RenderImagePath(doc, srcPath)
+		return opts.RenderImagePath(doc, srcPath)
+	}
 
-		return resource, true
+	readEXIF := opts.ReadEXIF
+	if readEXIF == nil {
+		readEXIF = images.ReadEXIFFromFile
 	}
 
 	RecodePaths(doc, toResource)
 
 	// Must be executed in this order, because GPX requires populated galleries.
-	EmplaceGalleries(doc, toResource)
-	EmplaceGPXMaps(doc, toResource)
-	EmplaceVideos(doc, toResource)
+	if err := EmplaceGalleries(doc, toResource, readEXIF); err != nil {
+		return data.NewDocumentError(doc.Path, data.StageGallery, err)
+	}
+
+	if err := EmplaceGPXMaps(doc, toResource); err != nil {
+		return data.NewDocumentError(doc.Path, data.StageGPX, err)
+	}
+
+	if err := EmplaceVideos(doc, toResource); err != nil {
+		return data.NewDocumentError(doc.Path, data.StageVideo, err)
+	}
 
 	if len(doc.Maps) == 0 {
-		InsertTracklessMap(doc)
+		if err := InsertTracklessMap(doc); err != nil {
+			return data.NewDocumentError(doc.Path, data.StageGPX, err)
+		}
 	}
 
 	doc.IsHtmlProcessed = true
+
+	return nil
 }
@@ -5,8 +5,10 @@ import (
 	"html/template"
 	"time"
 
+	"github.com/bgraf/rueckblick/config"
 	"github.com/bgraf/rueckblick/data"
 	"github.com/bgraf/rueckblick/res"
+	"github.com/bgraf/rueckblick/tiles"
 )
 
 type Filenamer interface {
@@ -20,7 +22,26 @@ func EntryURL(f Filenamer, doc *data.Document) string {
 }
 
 func PreviewURL(f Filenamer, doc *data.Document) string {
-	return fmt.Sprintf("file://%s", doc.PreviewAbsolutePath())
+	return doc.PreviewResource.URI
+}
+
+// LabeledField is a custom front matter field declared with a label.
+type LabeledField struct {
+	Label string
+	Field *data.Field
+}
+
+// LabeledFields returns the fields of the document declared with a label in the order of
+// their declaration.
+func LabeledFields(doc *data.Document, schemas []config.FieldSchema) []LabeledField {
+	var fields []LabeledField
+	for _, schema := range schemas {
+		if field := doc.Field(schema.Name); field != nil && schema.Label != "" && field.String() != "" {
+			fields = append(fields, LabeledField{Label: schema.Label, Field: field})
+		}
+	}
+
+	return fields
 }
 
 func ReadTemplates(f Filenamer) (*template.Template, error) {
@@ -43,6 +64,19 @@ func ReadTemplates(f Filenamer) (*template.Template, error) {
 		return template.URL(f.CalendarFile(y, int(m)))
 	}
 
+	funcMap["hasFeed"] = config.HasFeedBaseURL
+	funcMap["hasGlobalMap"] = config.HasHomeCoords
+
+	fieldSchemas := config.FieldSchemas()
+	funcMap["labeledFields"] = func(doc *data.Document) []LabeledField {
+		return LabeledFields(doc, fieldSchemas)
+	}
+
+	tileProviders := tiles.Providers(config.BuildDirectory())
+	funcMap["tileProviders"] = func() []config.TileProvider {
+		return tileProviders
+	}
+
 	templates, err := template.New("").Funcs(funcMap).ParseFS(res.Templates, "templates/*")
 	if err != nil {
 		return nil, fmt.Errorf("failed to load templates: %w", err)
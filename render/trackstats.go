@@ -0,0 +1,119 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bgraf/rueckblick/geotrack"
+)
+
+// Dimensions of the coordinate system of elevation profiles, which are scaled to the width
+// of the page.
+const (
+	profileWidth     = 600
+	profileHeight    = 100
+	profileMaxPoints = 300
+)
+
+// trackSummaryHTML renders a box summarizing the statistics of the track followed by its
+// elevation profile, if the track has elevation data.
+func trackSummaryHTML(c *geotrack.Collection) string {
+	if len(c.Points()) < 2 {
+		return ""
+	}
+
+	stats := c.Statistics()
+
+	var buf bytes.Buffer
+
+	_, _ = buf.WriteString(`<div class="track-summary">`)
+
+	item := func(label, value string) {
+		_, _ = fmt.Fprintf(
+			&buf,
+			`<div class="track-summary-item"><span class="track-summary-label">%s</span> <span class="track-summary-value">%s</span></div>`,
+			html.EscapeString(label),
+			html.EscapeString(value),
+		)
+	}
+
+	item("Strecke", formatDecimal(stats.Distance/1000, 1)+" km")
+
+	if stats.Duration > 0 {
+		item("Dauer", formatDuration(stats.Duration))
+		item("In Bewegung", formatDuration(stats.MovingTime))
+		item("Ø Geschwindigkeit", formatDecimal(stats.MovingSpeed()*3.6, 1)+" km/h")
+	}
+
+	if stats.HasElevation {
+		item("Anstieg", formatDecimal(stats.Ascent, 0)+" m")
+		item("Abstieg", formatDecimal(stats.Descent, 0)+" m")
+		item("Höhe", fmt.Sprintf("%s – %s m", formatDecimal(stats.MinElevation, 0), formatDecimal(stats.MaxElevation, 0)))
+	}
+
+	_, _ = buf.WriteString(`</div>`)
+
+	_, _ = buf.WriteString(elevationProfileSVG(c.ElevationProfile(), stats))
+
+	return buf.String()
+}
+
+// elevationProfileSVG renders the profile as inline SVG area chart.
+func elevationProfileSVG(profile []geotrack.ProfilePoint, stats geotrack.Statistics) string {
+	if len(profile) < 2 {
+		return ""
+	}
+
+	totalDistance := profile[len(profile)-1].Distance
+	if totalDistance <= 0 {
+		return ""
+	}
+
+	// Keep some space above and below the line.
+	elevationRange := max(stats.MaxElevation-stats.MinElevation, 10)
+	bottom := stats.MinElevation - 0.1*elevationRange
+	top := stats.MaxElevation + 0.1*elevationRange
+
+	step := max(1, len(profile)/profileMaxPoints)
+
+	var coords []string
+	for i := 0; i < len(profile); i += step {
+		coords = append(coords, profileCoordinate(profile[i], totalDistance, bottom, top))
+	}
+	if (len(profile)-1)%step != 0 {
+		coords = append(coords, profileCoordinate(profile[len(profile)-1], totalDistance, bottom, top))
+	}
+
+	line := strings.Join(coords, " ")
+	area := fmt.Sprintf("0,%d %s %d,%d", profileHeight, line, profileWidth, profileHeight)
+
+	return fmt.Sprintf(
+		`<svg class="elevation-profile" viewBox="0 0 %d %d" preserveAspectRatio="none">`+
+			`<polygon class="elevation-profile-area" points="%s"/>`+
+			`<polyline class="elevation-profile-line" points="%s" vector-effect="non-scaling-stroke"/>`+
+			`</svg>`,
+		profileWidth, profileHeight, area, line,
+	)
+}
+
+func profileCoordinate(p geotrack.ProfilePoint, totalDistance, bottom, top float64) string {
+	x := p.Distance / totalDistance * profileWidth
+	y := profileHeight - (p.Elevation-bottom)/(top-bottom)*profileHeight
+
+	return strconv.FormatFloat(x, 'f', 1, 64) + "," + strconv.FormatFloat(y, 'f', 1, 64)
+}
+
+// formatDecimal formats the value with German decimal separator.
+func formatDecimal(v float64, prec int) string {
+	return strings.Replace(strconv.FormatFloat(v, 'f', prec, 64), ".", ",", 1)
+}
+
+// formatDuration formats the duration as hours and minutes, e.g., `2:05 h`.
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	return fmt.Sprintf("%d:%02d h", int(d.Hours()), int(d.Minutes())%60)
+}
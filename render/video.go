@@ -3,7 +3,6 @@ package render
 import (
 	"bytes"
 	"fmt"
-	"log"
 	"path"
 	"strings"
 
@@ -16,18 +15,27 @@ import (
 const VideoTagName = "rb-video"
 const VideoSrcAttributeName = "src"
 
-func EmplaceVideos(doc *data.Document, toResource MapToResourceFunc) {
-	doc.HTML.Find(VideoTagName).Each(func(i int, s *goquery.Selection) {
+// EmplaceVideos replaces each `<rb-video src="..." />` node by an HTML video player.
+func EmplaceVideos(doc *data.Document, toResource MapToResourceFunc) error {
+	var err error
+
+	doc.HTML.Find(VideoTagName).EachWithBreak(func(i int, s *goquery.Selection) bool {
 		srcAttr := strings.TrimSpace(s.AttrOr(VideoSrcAttributeName, ""))
 		if len(srcAttr) == 0 {
-			log.Printf("Cannot emplace video with missing src-attribute\n")
-			return
+			err = fmt.Errorf("video with missing src-attribute")
+			return false
 		}
 
 		if !path.IsAbs(srcAttr) {
 			srcAttr = path.Join(doc.DocumentDirectory(), srcAttr)
 		}
 
+		resource, ok := toResource(srcAttr)
+		if !ok {
+			err = fmt.Errorf("video with missing source file '%s'", srcAttr)
+			return false
+		}
+
 		// TODO: extract text node and use it as caption
 		var texts []string
 		for node := range s.Nodes[0].ChildNodes() {
@@ -40,11 +48,15 @@ func EmplaceVideos(doc *data.Document, toResource MapToResourceFunc) {
 
 		_, _ = buf.WriteString("<figure>")
 		_, _ = buf.WriteString("<video controls>")
-		_, _ = buf.WriteString(fmt.Sprintf("<source src=\"%s\" type=\"video/mp4\">", srcAttr))
+		_, _ = buf.WriteString(fmt.Sprintf("<source src=\"%s\" type=\"video/mp4\">", resource.URI))
 		_, _ = buf.WriteString("</video>")
 		_, _ = buf.WriteString(fmt.Sprintf("<figcaption>%s</figcaption>", strings.Join(texts, " ")))
 		_, _ = buf.WriteString("</figure>")
 
 		s.ReplaceWithHtml(buf.String())
+
+		return true
 	})
+
+	return err
 }
@@ -30,14 +30,33 @@
     }
 })();
 
+// addTileLayers adds the first of the configured tile providers to the map and returns
+// the layers of all providers by name, such that they can be selected.
+function addTileLayers(map) {
+    const baseLayers = {};
+
+    tileProviders.forEach(function (provider, i) {
+        const layer = L.tileLayer(provider.url, {
+            attribution: provider.attribution,
+            maxZoom: provider.maxZoom,
+        });
+
+        if (i === 0) {
+            layer.addTo(map);
+        }
+
+        baseLayers[provider.name] = layer;
+    });
+
+    return tileProviders.length > 1 ? baseLayers : {};
+}
+
 function mountMap(container, data) {
     let map = L.map(container, {
         scrollWheelZoom: false,
     });
 
-    L.tileLayer('https://{s}.tile.openstreetmap.org/{z}/{x}/{y}.png', {
-        attribution: '&copy; <a href="https://www.openstreetmap.org/copyright">OpenStreetMap</a> contributors'
-    }).addTo(map);
+    const baseLayers = addTileLayers(map);
 
     map.on('focus', function() { map.scrollWheelZoom.enable(); });
     map.on('blur', function() { map.scrollWheelZoom.disable(); });
@@ -46,16 +65,62 @@ function mountMap(container, data) {
     const overlayLayers = {}
     const focusControlLayers = [];
 
-    if (data.track) {
-        let latLngs = data.track;
-        const polyline = L.polyline(latLngs, { color: 'blue' }).addTo(map);
-        overlayLayers.Track = polyline;
-        focusControlLayers.push(polyline);
+    // Segments are passed as separate lines, so gaps in the recording are not bridged.
+    if (data.tracks && data.tracks.length > 0) {
+        const colors = ['blue', 'darkred', 'darkgreen', 'purple', 'darkorange'];
+        const tracks = data.tracks.map(function (track, i) {
+            const polyline = L.polyline(track.segments, { color: colors[i % colors.length] });
+            if (track.name) {
+                polyline.bindTooltip(track.name, { sticky: true });
+            }
+            return polyline;
+        });
+
+        overlayLayers.Track = L.featureGroup(tracks).addTo(map);
+        focusControlLayers.push(overlayLayers.Track);
+    }
+
+    if (data.routes && data.routes.length > 0) {
+        const routes = data.routes.map(function (route) {
+            const polyline = L.polyline(route.segments, { color: 'gray', dashArray: '6 6' });
+            if (route.name) {
+                polyline.bindTooltip(route.name, { sticky: true });
+            }
+            return polyline;
+        });
+
+        overlayLayers.Routen = L.featureGroup(routes).addTo(map);
+        focusControlLayers.push(overlayLayers.Routen);
+    }
+
+    if (data.waypoints && data.waypoints.length > 0) {
+        const waypoints = data.waypoints.map(function (waypoint) {
+            const marker = L.circleMarker(waypoint.LatLng, {
+                radius: 6,
+                color: 'white',
+                weight: 2,
+                fillColor: 'darkred',
+                fillOpacity: 1,
+            });
+
+            const popupContainer = L.DomUtil.create('div', 'gpx-map-waypoint');
+            const name = L.DomUtil.create('strong', '', popupContainer);
+            name.textContent = waypoint.Name;
+            if (waypoint.Description) {
+                const description = L.DomUtil.create('div', '', popupContainer);
+                description.textContent = waypoint.Description;
+            }
+            marker.bindPopup(popupContainer);
+
+            return marker;
+        });
+
+        overlayLayers.Wegpunkte = L.featureGroup(waypoints).addTo(map);
+        focusControlLayers.push(overlayLayers.Wegpunkte);
     }
 
     if (data.images) {
         const markers = data.images.map(function (img) {
-            console.log("BLA", img);
             let marker = L.marker(img.LatLng);
 
             let popupContainer = L.DomUtil.create('div', 'gpx-map-marker');
@@ -73,7 +138,7 @@ function mountMap(container, data) {
         focusControlLayers.push(overlayLayers.Photos);
     }
 
-    L.control.layers({}, overlayLayers).addTo(map);
+    L.control.layers(baseLayers, overlayLayers).addTo(map);
 
     L.control.focusControl(
         () => {
@@ -95,67 +160,319 @@ function mountMap(container, data) {
     mapFitBounds();
 }
 
+// Heat layer drawing the point counts of grid cells given as [lat, lon, count] onto a
+// canvas, which is redrawn whenever the map moves.
+L.HeatLayer = L.Layer.extend({
+    options: {
+        radius: 15,
+        gradient: { 0.4: 'blue', 0.6: 'cyan', 0.7: 'lime', 0.8: 'yellow', 1.0: 'red' },
+    },
+
+    initialize: function (cells, options) {
+        this._cells = cells;
+        L.setOptions(this, options);
+    },
+
+    setCells: function (cells) {
+        this._cells = cells;
+        if (this._map) {
+            this._redraw();
+        }
+        return this;
+    },
+
+    onAdd: function (map) {
+        this._canvas = L.DomUtil.create('canvas', 'gpx-map-heat leaflet-zoom-hide');
+        map.getPanes().overlayPane.appendChild(this._canvas);
+        map.on('moveend resize', this._redraw, this);
+        this._redraw();
+    },
+
+    onRemove: function (map) {
+        L.DomUtil.remove(this._canvas);
+        map.off('moveend resize', this._redraw, this);
+    },
+
+    _palette: function () {
+        if (!this._colors) {
+            const canvas = L.DomUtil.create('canvas');
+            canvas.width = 256;
+            canvas.height = 1;
+
+            const ctx = canvas.getContext('2d');
+            const gradient = ctx.createLinearGradient(0, 0, 256, 0);
+            for (let stop in this.options.gradient) {
+                gradient.addColorStop(+stop, this.options.gradient[stop]);
+            }
+            ctx.fillStyle = gradient;
+            ctx.fillRect(0, 0, 256, 1);
+
+            this._colors = ctx.getImageData(0, 0, 256, 1).data;
+        }
+        return this._colors;
+    },
+
+    _redraw: function () {
+        const map = this._map;
+        const size = map.getSize();
+        const r = this.options.radius;
+
+        L.DomUtil.setPosition(this._canvas, map.containerPointToLayerPoint([0, 0]));
+        this._canvas.width = size.x;
+        this._canvas.height = size.y;
+
+        // Cells closer than half the radius on the screen are drawn as one.
+        const bins = new Map();
+        let maxCount = 0;
+
+        this._cells.forEach(function (cell) {
+            const p = map.latLngToContainerPoint([cell[0], cell[1]]);
+            if (p.x < -r || p.y < -r || p.x > size.x + r || p.y > size.y + r) {
+                return;
+            }
+
+            const key = Math.round(2 * p.x / r) + ':' + Math.round(2 * p.y / r);
+            let bin = bins.get(key);
+            if (!bin) {
+                bin = { x: p.x, y: p.y, count: 0 };
+                bins.set(key, bin);
+            }
+            bin.count += cell[2];
+            maxCount = Math.max(maxCount, bin.count);
+        });
+
+        const ctx = this._canvas.getContext('2d');
+        if (bins.size === 0) {
+            return;
+        }
+
+        // Counts are scaled logarithmically, such that places visited once remain visible
+        // next to places visited often.
+        bins.forEach(function (bin) {
+            const gradient = ctx.createRadialGradient(bin.x, bin.y, 0, bin.x, bin.y, r);
+            gradient.addColorStop(0, 'rgba(0, 0, 0, 1)');
+            gradient.addColorStop(1, 'rgba(0, 0, 0, 0)');
+
+            ctx.globalAlpha = Math.max(0.1, Math.log1p(bin.count) / Math.log1p(maxCount));
+            ctx.fillStyle = gradient;
+            ctx.fillRect(bin.x - r, bin.y - r, 2 * r, 2 * r);
+        });
+
+        const colors = this._palette();
+        const image = ctx.getImageData(0, 0, size.x, size.y);
+        const pixels = image.data;
+
+        for (let i = 3; i < pixels.length; i += 4) {
+            const alpha = pixels[i];
+            if (alpha > 0) {
+                pixels[i - 3] = colors[4 * alpha];
+                pixels[i - 2] = colors[4 * alpha + 1];
+                pixels[i - 1] = colors[4 * alpha + 2];
+                pixels[i] = Math.min(255, 64 + alpha);
+            }
+        }
+
+        ctx.putImageData(image, 0, 0);
+    },
+});
+
+L.heatLayer = function (cells, options) {
+    return new L.HeatLayer(cells, options);
+};
+
+// Control selecting the year and tag of the entries shown on the global map.
+L.Control.EntryFilter = L.Control.extend({
+    options: {
+        position: 'topright',
+    },
+
+    initialize: function (entries, onChange, options) {
+        this._entries = entries;
+        this._onChange = onChange;
+        L.setOptions(this, options);
+    },
+
+    onAdd: function (map) {
+        const el = L.DomUtil.create('div', 'leaflet-bar leaflet-control gpx-map-filter');
+        L.DomEvent.disableClickPropagation(el);
+
+        const years = [...new Set(this._entries.map(entry => entry.year))].sort((a, b) => b - a);
+        const tags = [...new Set(this._entries.flatMap(entry => entry.tags || []))].sort((a, b) => a.localeCompare(b));
+
+        const filter = { year: null, tag: null };
+        const onChange = this._onChange;
+
+        function addSelect(label, values, key) {
+            const select = L.DomUtil.create('select', '', el);
+            const all = L.DomUtil.create('option', '', select);
+            all.value = '';
+            all.textContent = label;
+
+            values.forEach(function (value) {
+                const option = L.DomUtil.create('option', '', select);
+                option.value = value;
+                option.textContent = value;
+            });
+
+            select.addEventListener('change', function () {
+                filter[key] = select.value === '' ? null : select.value;
+                onChange(filter);
+            });
+        }
+
+        addSelect('Alle Jahre', years, 'year');
+        if (tags.length > 0) {
+            addSelect('Alle Tags', tags, 'tag');
+        }
+
+        return el;
+    },
+});
+
+L.control.entryFilter = function (entries, onChange, options) {
+    return new L.Control.EntryFilter(entries, onChange, options);
+};
+
+// Markers closer than this number of pixels on the screen are combined into a cluster.
+const clusterSize = 60;
+
+// clusterMarkers fills the layer with markers of the entries, combining entries close to
+// each other at the map's zoom level into a single marker showing their number.
+function clusterMarkers(map, layer, entries) {
+    layer.clearLayers();
+
+    const cells = new Map();
+    entries.forEach(function (entry) {
+        const p = map.project(entry.marker, map.getZoom());
+        const key = Math.floor(p.x / clusterSize) + ':' + Math.floor(p.y / clusterSize);
+        if (!cells.has(key)) {
+            cells.set(key, []);
+        }
+        cells.get(key).push(entry);
+    });
+
+    cells.forEach(function (members) {
+        if (members.length === 1 || map.getZoom() >= map.getMaxZoom()) {
+            members.forEach(entry => layer.addLayer(entryMarker(entry)));
+            return;
+        }
+
+        const bounds = L.latLngBounds(members.map(entry => entry.marker));
+        const icon = L.divIcon({
+            className: 'gpx-map-cluster',
+            html: '<span>' + members.length + '</span>',
+            iconSize: L.point(36, 36),
+        });
+
+        const marker = L.marker(bounds.getCenter(), { icon: icon });
+        marker.bindTooltip(members.map(entry => entry.title).join('<br>'));
+        marker.on('click', () => map.fitBounds(bounds.pad(0.2)));
+
+        layer.addLayer(marker);
+    });
+}
+
+function entryMarker(entry) {
+    const marker = L.marker(entry.marker);
+
+    const popupContainer = L.DomUtil.create('div', 'gpx-map-marker');
+
+    const popupAnchor = L.DomUtil.create('a', '', popupContainer);
+    popupAnchor.title = entry.title;
+    popupAnchor.href = entry.uri;
+
+    const popupImage = L.DomUtil.create('img', '', popupAnchor);
+    popupImage.src = entry.preview;
+
+    marker.bindPopup(popupContainer);
+
+    return marker;
+}
+
 function mountGlobalMap(container, data) {
     let map = L.map(container, {
         scrollWheelZoom: false,
     });
 
-    L.tileLayer('https://{s}.tile.openstreetmap.org/{z}/{x}/{y}.png', {
-        attribution: '&copy; <a href="https://www.openstreetmap.org/copyright">OpenStreetMap</a> contributors'
-    }).addTo(map);
+    const baseLayers = addTileLayers(map);
 
     map.on('focus', function() { map.scrollWheelZoom.enable(); });
     map.on('blur', function() { map.scrollWheelZoom.disable(); });
 
-    let bounds = null;
-    const overlayLayers = {};
-
-    if (data) {
-        const groups = new Map();
+    const entries = data.entries || [];
+    let shown = entries;
 
-        const markers = data.map(function (img) {
-            let marker = L.marker(img.LatLng);
+    const overlayLayers = {};
 
-            let popupContainer = L.DomUtil.create('div', 'gpx-map-marker');
+    const markers = L.layerGroup().addTo(map);
+    overlayLayers['Einträge'] = markers;
 
-            let popupAnchor = L.DomUtil.create('a', '', popupContainer);
-            popupAnchor.title = img.Title;
-            popupAnchor.href = img.URI;
+    const tracks = L.featureGroup();
+    if (data.tracks) {
+        overlayLayers.Tracks = tracks.addTo(map);
+    }
 
-            let popupImage = L.DomUtil.create('img', '', popupAnchor);
-            popupImage.src = img.Preview;
+    const heat = L.heatLayer([]);
+    if (data.heatmap) {
+        overlayLayers.Heatmap = heat;
+    }
 
-            marker.bindPopup(popupContainer);
+    function bounds() {
+        const b = L.latLngBounds([]);
+        shown.forEach(function (entry) {
+            if (entry.marker) {
+                b.extend(entry.marker);
+            }
+            if (entry.tracks && map.hasLayer(tracks)) {
+                entry.tracks.forEach(segment => segment.forEach(p => b.extend(p)));
+            }
+        });
+        return b;
+    }
 
-            if (groups[img.Year] === undefined) { 
-                groups[img.Year] = [marker];
-            } else {
-                groups[img.Year].push(marker);
+    function update() {
+        tracks.clearLayers();
+        shown.forEach(function (entry) {
+            if (!entry.tracks) {
+                return;
             }
 
-            return marker;
+            const polyline = L.polyline(entry.tracks, { color: 'darkred', weight: 2, opacity: 0.7 });
+            polyline.bindTooltip(entry.title, { sticky: true });
+            polyline.on('click', () => { window.location.href = entry.uri; });
+            tracks.addLayer(polyline);
         });
 
-        for (let k in groups) {
-            const v = groups[k];
-            overlayLayers["" + k] = L.layerGroup(v).addTo(map);
-        }
-
-        bounds = L.latLngBounds(markers.map(marker => marker.getLatLng()));
+        heat.setCells(shown.flatMap(entry => entry.heat || []));
 
+        clusterMarkers(map, markers, shown.filter(entry => entry.marker));
     }
 
-    L.control.layers({}, overlayLayers).addTo(map);
+    L.control.layers(baseLayers, overlayLayers).addTo(map);
+
+    L.control.entryFilter(entries, function (filter) {
+        shown = entries.filter(entry =>
+            (filter.year === null || String(entry.year) === filter.year) &&
+            (filter.tag === null || (entry.tags || []).includes(filter.tag))
+        );
+        update();
+    }).addTo(map);
 
     L.control.focusControl(
-        () => bounds,
+        bounds,
         { position: 'topleft' }
     ).addTo(map);
 
-    function mapFitBounds() {
-        map.fitBounds(bounds);
+    // Markers are clustered at the current zoom level, so the view must be set first.
+    const initialBounds = bounds();
+    if (initialBounds.isValid()) {
+        map.fitBounds(initialBounds);
+    } else {
+        map.setView([0, 0], 2);
     }
-    mapFitBounds();
+
+    map.on('zoomend', () => clusterMarkers(map, markers, shown.filter(entry => entry.marker)));
+    update();
 }
 
 function loadAndMountMap(container, opts) {
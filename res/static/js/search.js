@@ -0,0 +1,122 @@
+/**
+ * Client-side full-text search over `window.searchIndex`, see `building/search.go` for the
+ * record layout.
+ */
+(function () {
+    const maxResults = 50;
+    const snippetRadius = 80;
+
+    function normalize(s) {
+        return (s || '').normalize('NFD').replace(/[\u0300-\u036f]/g, '').toLowerCase();
+    }
+
+    const records = (window.searchIndex || []).map(function (r) {
+        return {
+            record: r,
+            title: normalize(r.t),
+            abstract: normalize(r.a),
+            tags: normalize((r.g || []).join(' ')),
+            text: normalize(r.x),
+        };
+    });
+
+    function score(entry, terms) {
+        let total = 0;
+        for (const term of terms) {
+            let s = 0;
+            if (entry.title.includes(term)) s += 10;
+            if (entry.tags.includes(term)) s += 5;
+            if (entry.abstract.includes(term)) s += 3;
+            if (entry.text.includes(term)) s += 1;
+            if (s === 0) {
+                return 0;
+            }
+            total += s;
+        }
+        return total;
+    }
+
+    function snippet(entry, terms) {
+        const text = entry.record.x || '';
+        let pos = -1;
+        for (const term of terms) {
+            pos = entry.text.indexOf(term);
+            if (pos >= 0) break;
+        }
+        if (pos < 0) {
+            return text.substring(0, 2 * snippetRadius);
+        }
+        const from = Math.max(0, pos - snippetRadius);
+        const to = Math.min(text.length, pos + snippetRadius);
+        return (from > 0 ? '… ' : '') + text.substring(from, to) + (to < text.length ? ' …' : '');
+    }
+
+    function renderResult(entry, terms) {
+        const r = entry.record;
+        const container = document.createElement('div');
+        container.className = 'search-result';
+
+        const titleBar = document.createElement('div');
+        titleBar.className = 'entry-title-bar';
+        const date = document.createElement('div');
+        date.className = 'entry-date';
+        date.textContent = r.d;
+        const link = document.createElement('a');
+        link.href = r.u;
+        const title = document.createElement('h2');
+        title.textContent = r.t;
+        link.appendChild(title);
+        titleBar.appendChild(date);
+        titleBar.appendChild(link);
+        container.appendChild(titleBar);
+
+        if (r.a) {
+            const abstract = document.createElement('div');
+            abstract.className = 'abstract';
+            abstract.textContent = r.a;
+            container.appendChild(abstract);
+        }
+
+        const text = document.createElement('div');
+        text.className = 'search-snippet';
+        text.textContent = snippet(entry, terms);
+        container.appendChild(text);
+
+        return container;
+    }
+
+    function search(query) {
+        const results = document.getElementById('search-results');
+        const summary = document.getElementById('search-summary');
+        results.replaceChildren();
+
+        const terms = normalize(query).split(/\s+/).filter(t => t.length > 0);
+        if (terms.length === 0) {
+            summary.textContent = '';
+            return;
+        }
+
+        const matches = records
+            .map(entry => ({ entry: entry, score: score(entry, terms) }))
+            .filter(m => m.score > 0)
+            .sort((a, b) => b.score - a.score || b.entry.record.d.localeCompare(a.entry.record.d));
+
+        summary.textContent = matches.length + ' Treffer';
+
+        for (const m of matches.slice(0, maxResults)) {
+            results.appendChild(renderResult(m.entry, terms));
+        }
+    }
+
+    const input = document.getElementById('search-input');
+    const params = new URLSearchParams(window.location.search);
+    if (params.has('q')) {
+        input.value = params.get('q');
+    }
+
+    input.addEventListener('input', function () {
+        search(input.value);
+    });
+
+    search(input.value);
+})();
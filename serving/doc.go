@@ -0,0 +1,3 @@
+// Package serving serves a build directory over HTTP and reloads open pages once the
+// build output changes.
+package serving
@@ -0,0 +1,49 @@
+package serving
+
+import (
+	"net/http"
+	"sync"
+)
+
+// ChangeNotifier answers the long-polling `/changed` requests issued by `livereload.js`.
+// Requests stay pending until `Notify` is called.
+type ChangeNotifier struct {
+	mu      sync.Mutex
+	waiters map[chan struct{}]struct{}
+}
+
+func NewChangeNotifier() *ChangeNotifier {
+	return &ChangeNotifier{
+		waiters: make(map[chan struct{}]struct{}),
+	}
+}
+
+// Notify finishes all pending requests.
+func (n *ChangeNotifier) Notify() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for ch := range n.waiters {
+		close(ch)
+	}
+
+	n.waiters = make(map[chan struct{}]struct{})
+}
+
+func (n *ChangeNotifier) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ch := make(chan struct{})
+
+	n.mu.Lock()
+	n.waiters[ch] = struct{}{}
+	n.mu.Unlock()
+
+	select {
+	case <-ch:
+		w.Header().Set("Cache-Control", "no-store")
+		_, _ = w.Write([]byte("changed"))
+	case <-r.Context().Done():
+		n.mu.Lock()
+		delete(n.waiters, ch)
+		n.mu.Unlock()
+	}
+}
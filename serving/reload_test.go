@@ -0,0 +1,89 @@
+package serving
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// waitForWaiters waits until the given number of requests are pending.
+func waitForWaiters(t *testing.T, n *ChangeNotifier, count int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		n.mu.Lock()
+		pending := len(n.waiters)
+		n.mu.Unlock()
+
+		if pending == count {
+			return
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("want %d pending requests", count)
+}
+
+func TestChangeNotifier(t *testing.T) {
+	n := NewChangeNotifier()
+
+	recorders := make([]*httptest.ResponseRecorder, 2)
+	done := make(chan struct{})
+
+	for i := range recorders {
+		recorders[i] = httptest.NewRecorder()
+
+		go func(rec *httptest.ResponseRecorder) {
+			n.ServeHTTP(rec, httptest.NewRequest("GET", "/changed", nil))
+			done <- struct{}{}
+		}(recorders[i])
+	}
+
+	waitForWaiters(t, n, len(recorders))
+
+	n.Notify()
+
+	for range recorders {
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("pending request not answered")
+		}
+	}
+
+	for _, rec := range recorders {
+		if rec.Body.String() != "changed" {
+			t.Errorf("got body %q, want changed", rec.Body.String())
+		}
+	}
+
+	waitForWaiters(t, n, 0)
+}
+
+func TestChangeNotifierCanceled(t *testing.T) {
+	n := NewChangeNotifier()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rec := httptest.NewRecorder()
+	done := make(chan struct{})
+
+	go func() {
+		n.ServeHTTP(rec, httptest.NewRequest("GET", "/changed", nil).WithContext(ctx))
+		close(done)
+	}()
+
+	waitForWaiters(t, n, 1)
+
+	cancel()
+	<-done
+
+	// A canceled request is forgotten and not answered.
+	waitForWaiters(t, n, 0)
+
+	if rec.Body.Len() != 0 {
+		t.Errorf("got body %q for canceled request", rec.Body.String())
+	}
+}
@@ -0,0 +1,83 @@
+package serving
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// Script tag inserted into every served HTML page.
+const liveReloadScript = `<script src="./res/static/js/livereload.js"></script>`
+
+// NewHandler returns a handler serving the files of the build directory. HTML pages are
+// extended by the live reload script, which is answered by the given notifier.
+func NewHandler(buildDirectory string, notifier *ChangeNotifier) http.Handler {
+	root := http.Dir(buildDirectory)
+	fileServer := http.FileServer(root)
+
+	mux := http.NewServeMux()
+	mux.Handle("/changed", notifier)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Path
+		if strings.HasSuffix(name, "/") {
+			name = path.Join(name, "index.html")
+		}
+
+		if path.Ext(name) != ".html" {
+			fileServer.ServeHTTP(w, r)
+			return
+		}
+
+		content, modTime, err := readFile(root, name)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		http.ServeContent(w, r, name, modTime, bytes.NewReader(injectLiveReload(content)))
+	})
+
+	return mux
+}
+
+func readFile(root http.FileSystem, name string) ([]byte, time.Time, error) {
+	f, err := root.Open(name)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	defer func() { _ = f.Close() }()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	content, err := io.ReadAll(f)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	return content, fi.ModTime(), nil
+}
+
+func injectLiveReload(content []byte) []byte {
+	marker := []byte("</body>")
+
+	i := bytes.LastIndex(content, marker)
+	if i < 0 {
+		return append(content, liveReloadScript...)
+	}
+
+	var buf bytes.Buffer
+	buf.Grow(len(content) + len(liveReloadScript))
+	buf.Write(content[:i])
+	buf.WriteString(liveReloadScript)
+	buf.Write(content[i:])
+
+	return buf.Bytes()
+}
@@ -0,0 +1,73 @@
+package serving
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInjectLiveReload(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"<html><body>x</body></html>", "<html><body>x" + liveReloadScript + "</body></html>"},
+		{"<body><p>&lt;/body&gt;</p></body>", "<body><p>&lt;/body&gt;</p>" + liveReloadScript + "</body>"},
+		{"<p>fragment</p>", "<p>fragment</p>" + liveReloadScript},
+	}
+
+	for _, tt := range tests {
+		if got := string(injectLiveReload([]byte(tt.content))); got != tt.want {
+			t.Errorf("injectLiveReload(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	buildDirectory := t.TempDir()
+
+	files := map[string]string{
+		"index.html":    "<html><body>index</body></html>",
+		"entry.html":    "<html><body>entry</body></html>",
+		"res/style.css": "body {}",
+	}
+
+	for name, content := range files {
+		path := filepath.Join(buildDirectory, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(content), 0o666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	handler := NewHandler(buildDirectory, NewChangeNotifier())
+
+	tests := []struct {
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"/", 200, "<html><body>index" + liveReloadScript + "</body></html>"},
+		{"/entry.html", 200, "<html><body>entry" + liveReloadScript + "</body></html>"},
+		{"/res/style.css", 200, "body {}"},
+		{"/missing.html", 404, ""},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+
+		if rec.Code != tt.wantStatus {
+			t.Errorf("%s: got status %d, want %d", tt.path, rec.Code, tt.wantStatus)
+			continue
+		}
+
+		if tt.wantStatus == 200 && rec.Body.String() != tt.wantBody {
+			t.Errorf("%s: got %q, want %q", tt.path, rec.Body.String(), tt.wantBody)
+		}
+	}
+}
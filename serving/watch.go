@@ -0,0 +1,112 @@
+package serving
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bgraf/rueckblick/filesystem"
+	"github.com/fsnotify/fsnotify"
+)
+
+// Time to wait for further events before reporting a change, editors tend to emit
+// several events when saving a single file.
+const debounceDelay = 300 * time.Millisecond
+
+// Watch recursively watches the given directory and calls `onChange` after files changed.
+// Paths below one of the `ignore` directories are not reported. Watch blocks until the
+// context is canceled.
+func Watch(ctx context.Context, directory string, ignore []string, onChange func()) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("create watcher: %w", err)
+	}
+
+	defer func() { _ = watcher.Close() }()
+
+	directory = filepath.Clean(directory)
+
+	isIgnored := func(path string) bool {
+		for _, dir := range ignore {
+			if path == dir || strings.HasPrefix(path, dir+string(filepath.Separator)) {
+				return true
+			}
+		}
+
+		// The watched directory itself may be hidden, e.g., `~/.journal`.
+		return path != directory && isTemporaryFile(path)
+	}
+
+	addRecursive := func(root string) error {
+		return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if !d.IsDir() {
+				return nil
+			}
+
+			if isIgnored(path) {
+				return filepath.SkipDir
+			}
+
+			return watcher.Add(path)
+		})
+	}
+
+	if err := addRecursive(directory); err != nil {
+		return fmt.Errorf("watch directory: %w", err)
+	}
+
+	timer := time.NewTimer(debounceDelay)
+	timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+
+			if isIgnored(event.Name) || event.Op == fsnotify.Chmod {
+				continue
+			}
+
+			if event.Has(fsnotify.Create) && filesystem.IsDirectory(event.Name) {
+				if err := addRecursive(event.Name); err != nil {
+					log.Printf("could not watch '%s': %s", event.Name, err)
+				}
+			}
+
+			timer.Reset(debounceDelay)
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+
+			log.Printf("watch error: %s", err)
+
+		case <-timer.C:
+			onChange()
+		}
+	}
+}
+
+// isTemporaryFile reports whether the path denotes a hidden, backup, or swap file.
+func isTemporaryFile(path string) bool {
+	name := filepath.Base(path)
+
+	return strings.HasPrefix(name, ".") ||
+		strings.HasSuffix(name, "~") ||
+		strings.HasSuffix(name, ".swp") ||
+		strings.HasPrefix(name, "tmp-rb.")
+}
@@ -0,0 +1,72 @@
+package serving
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchHiddenDirectory(t *testing.T) {
+	directory := filepath.Join(t.TempDir(), ".journal")
+	if err := os.MkdirAll(filepath.Join(directory, "2023"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changed := make(chan struct{}, 1)
+	done := make(chan error)
+
+	go func() {
+		done <- Watch(ctx, directory, nil, func() {
+			select {
+			case changed <- struct{}{}:
+			default:
+			}
+		})
+	}()
+
+	// Give the watcher time to register the directories.
+	time.Sleep(100 * time.Millisecond)
+
+	tests := []struct {
+		file       string
+		wantChange bool
+	}{
+		{".doc.md.swp", false},
+		{"doc.md~", false},
+		{"doc.md", true},
+	}
+
+	for _, tt := range tests {
+		if err := os.WriteFile(filepath.Join(directory, "2023", tt.file), nil, 0o666); err != nil {
+			t.Fatal(err)
+		}
+
+		// Ignored files are expected to stay quiet beyond the debounce delay.
+		timeout := 2 * debounceDelay
+		if tt.wantChange {
+			timeout = 5 * time.Second
+		}
+
+		select {
+		case <-changed:
+			if !tt.wantChange {
+				t.Errorf("%s: unexpected change", tt.file)
+			}
+		case <-time.After(timeout):
+			if tt.wantChange {
+				t.Errorf("%s: no change reported", tt.file)
+			}
+		}
+	}
+
+	cancel()
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
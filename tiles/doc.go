@@ -0,0 +1,3 @@
+// Package tiles installs raster map tiles into the build directory, such that maps can be
+// shown without network access.
+package tiles
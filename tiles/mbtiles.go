@@ -0,0 +1,124 @@
+package tiles
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+
+	_ "modernc.org/sqlite"
+)
+
+// MBTiles is a read-only tile set in the MBTiles format, a SQLite database of tiles
+// addressed in the TMS scheme.
+type MBTiles struct {
+	db *sql.DB
+}
+
+// Metadata describes a tile set.
+type Metadata struct {
+	Name        string
+	Format      string // Extension of the tile images, e.g., png or jpg
+	Attribution string
+	MinZoom     int
+	MaxZoom     int
+}
+
+// OpenMBTiles opens the MBTiles file at the given path.
+func OpenMBTiles(path string) (*MBTiles, error) {
+	// The path is made absolute, since a relative one would be taken as host of the URI.
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	dsn := url.URL{Scheme: "file", Path: filepath.ToSlash(path), RawQuery: "mode=ro"}
+
+	db, err := sql.Open("sqlite", dsn.String())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Ping(); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return &MBTiles{db: db}, nil
+}
+
+func (m *MBTiles) Close() error {
+	return m.db.Close()
+}
+
+// Metadata reads the description of the tile set. Zoom levels missing from the metadata
+// table are determined from the tiles.
+func (m *MBTiles) Metadata() (Metadata, error) {
+	md := Metadata{Format: "png", MinZoom: -1, MaxZoom: -1}
+
+	rows, err := m.db.Query("SELECT name, value FROM metadata")
+	if err != nil {
+		return md, fmt.Errorf("read metadata: %w", err)
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			return md, fmt.Errorf("read metadata: %w", err)
+		}
+
+		switch name {
+		case "name":
+			md.Name = value
+		case "format":
+			md.Format = value
+		case "attribution":
+			md.Attribution = value
+		case "minzoom":
+			_, _ = fmt.Sscan(value, &md.MinZoom)
+		case "maxzoom":
+			_, _ = fmt.Sscan(value, &md.MaxZoom)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return md, fmt.Errorf("read metadata: %w", err)
+	}
+
+	if md.MinZoom < 0 || md.MaxZoom < 0 {
+		var minZoom, maxZoom sql.NullInt64
+
+		err := m.db.QueryRow("SELECT MIN(zoom_level), MAX(zoom_level) FROM tiles").Scan(&minZoom, &maxZoom)
+		if err != nil {
+			return md, fmt.Errorf("read zoom levels: %w", err)
+		}
+
+		md.MinZoom, md.MaxZoom = int(minZoom.Int64), int(maxZoom.Int64)
+	}
+
+	return md, nil
+}
+
+// Tile returns the image of the tile with the given XYZ coordinates, or nil if the tile
+// set does not contain it.
+func (m *MBTiles) Tile(z, x, y int) ([]byte, error) {
+	var tile []byte
+
+	// MBTiles count rows from the south.
+	row := 1<<z - 1 - y
+
+	err := m.db.QueryRow(
+		"SELECT tile_data FROM tiles WHERE zoom_level = ? AND tile_column = ? AND tile_row = ?",
+		z, x, row,
+	).Scan(&tile)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("read tile %d/%d/%d: %w", z, x, y, err)
+	}
+
+	return tile, nil
+}
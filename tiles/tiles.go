@@ -0,0 +1,234 @@
+package tiles
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/bgraf/rueckblick/config"
+	"github.com/bgraf/rueckblick/filesystem"
+)
+
+// Name of the directory of installed tiles within the build directory.
+const Directory = "tiles"
+
+// Name of the file describing the installed tiles.
+const manifestFile = "tiles.json"
+
+// BoundingBox is a rectangle of geographic coordinates.
+type BoundingBox struct {
+	MinLat, MinLon, MaxLat, MaxLon float64
+}
+
+// Tile identifies a tile in the XYZ scheme used by web maps.
+type Tile struct {
+	Z, X, Y int
+}
+
+// TilesInBox returns the tiles of the given zoom level covering the bounding box, extended
+// by margin tiles in every direction.
+func TilesInBox(box BoundingBox, zoom int, margin int) []Tile {
+	n := 1 << zoom
+
+	x0, y0 := tileXY(box.MaxLat, box.MinLon, zoom)
+	x1, y1 := tileXY(box.MinLat, box.MaxLon, zoom)
+
+	x0, y0 = max(x0-margin, 0), max(y0-margin, 0)
+	x1, y1 = min(x1+margin, n-1), min(y1+margin, n-1)
+
+	var tiles []Tile
+	for x := x0; x <= x1; x++ {
+		for y := y0; y <= y1; y++ {
+			tiles = append(tiles, Tile{Z: zoom, X: x, Y: y})
+		}
+	}
+
+	return tiles
+}
+
+// tileXY returns the tile containing the coordinates in the Web Mercator projection.
+func tileXY(lat, lon float64, zoom int) (int, int) {
+	n := float64(int(1) << zoom)
+
+	// Web Mercator is undefined at the poles.
+	lat = max(min(lat, 85.0511), -85.0511)
+	latRad := lat * math.Pi / 180
+
+	x := int((lon + 180) / 360 * n)
+	y := int((1 - math.Log(math.Tan(latRad)+1/math.Cos(latRad))/math.Pi) / 2 * n)
+
+	last := int(n) - 1
+
+	return min(max(x, 0), last), min(max(y, 0), last)
+}
+
+// ImportOptions selects the tiles to import from an MBTiles file.
+type ImportOptions struct {
+	Boxes   []BoundingBox
+	MinZoom int
+	MaxZoom int
+	Margin  int // Number of additional tiles around each bounding box
+}
+
+// Import copies the tiles covering the bounding boxes from the MBTiles file into the tile
+// directory of the build directory. It returns the number of written tiles.
+func Import(buildDirectory string, mbtilesPath string, opts ImportOptions) (int, error) {
+	m, err := OpenMBTiles(mbtilesPath)
+	if err != nil {
+		return 0, fmt.Errorf("open MBTiles: %w", err)
+	}
+
+	defer func() { _ = m.Close() }()
+
+	md, err := m.Metadata()
+	if err != nil {
+		return 0, err
+	}
+
+	if md.Format == "pbf" {
+		return 0, errors.New("vector tiles are not supported")
+	}
+
+	minZoom, maxZoom := max(opts.MinZoom, md.MinZoom), min(opts.MaxZoom, md.MaxZoom)
+	if minZoom > maxZoom {
+		return 0, fmt.Errorf("tile set has zoom levels %d to %d only", md.MinZoom, md.MaxZoom)
+	}
+
+	written := 0
+	seen := make(map[Tile]bool)
+
+	for _, box := range opts.Boxes {
+		for zoom := minZoom; zoom <= maxZoom; zoom++ {
+			for _, t := range TilesInBox(box, zoom, opts.Margin) {
+				if seen[t] {
+					continue
+				}
+				seen[t] = true
+
+				ok, err := installTile(m, buildDirectory, md.Format, t)
+				if err != nil {
+					return written, err
+				}
+
+				if ok {
+					written++
+				}
+			}
+		}
+	}
+
+	// Tiles of previous imports remain, thus extend the recorded zoom levels.
+	manifest := Manifest{Name: md.Name, Format: md.Format, Attribution: md.Attribution, MinZoom: minZoom, MaxZoom: maxZoom}
+	if previous, ok := ReadManifest(buildDirectory); ok && previous.Format == manifest.Format {
+		manifest.MinZoom = min(manifest.MinZoom, previous.MinZoom)
+		manifest.MaxZoom = max(manifest.MaxZoom, previous.MaxZoom)
+	}
+
+	if err := writeManifest(buildDirectory, manifest); err != nil {
+		return written, err
+	}
+
+	return written, nil
+}
+
+// installTile writes the tile, unless it is missing from the tile set or already
+// installed. It reports whether the tile was written.
+func installTile(m *MBTiles, buildDirectory string, format string, t Tile) (bool, error) {
+	data, err := m.Tile(t.Z, t.X, t.Y)
+	if err != nil || data == nil {
+		return false, err
+	}
+
+	path := filepath.Join(buildDirectory, Directory, fmt.Sprint(t.Z), fmt.Sprint(t.X), fmt.Sprintf("%d.%s", t.Y, format))
+
+	if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, data) {
+		return false, nil
+	}
+
+	if err := filesystem.CreateDirectoryIfNotExists(filepath.Dir(path)); err != nil {
+		return false, err
+	}
+
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return false, fmt.Errorf("write tile: %w", err)
+	}
+
+	return true, nil
+}
+
+// Manifest describes the tiles installed in the build directory.
+type Manifest struct {
+	Name        string `json:"name"`
+	Format      string `json:"format"`
+	Attribution string `json:"attribution"`
+	MinZoom     int    `json:"minZoom"`
+	MaxZoom     int    `json:"maxZoom"`
+}
+
+// ReadManifest reads the description of the installed tiles, if any.
+func ReadManifest(buildDirectory string) (Manifest, bool) {
+	var manifest Manifest
+
+	payloadBytes, err := os.ReadFile(filepath.Join(buildDirectory, Directory, manifestFile))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("could not read tile manifest: %s", err)
+		}
+		return manifest, false
+	}
+
+	if err := json.Unmarshal(payloadBytes, &manifest); err != nil {
+		log.Printf("could not parse tile manifest: %s", err)
+		return manifest, false
+	}
+
+	return manifest, true
+}
+
+func writeManifest(buildDirectory string, manifest Manifest) error {
+	payloadBytes, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	directory := filepath.Join(buildDirectory, Directory)
+	if err := filesystem.CreateDirectoryIfNotExists(directory); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(filepath.Join(directory, manifestFile), payloadBytes, 0o644); err != nil {
+		return fmt.Errorf("write tile manifest: %w", err)
+	}
+
+	return nil
+}
+
+// Providers returns the tile providers shown on maps. Installed tiles precede the
+// configured providers, such that maps work without network access.
+func Providers(buildDirectory string) []config.TileProvider {
+	providers := config.TileProviders()
+
+	manifest, ok := ReadManifest(buildDirectory)
+	if !ok {
+		return providers
+	}
+
+	name := manifest.Name
+	if name == "" {
+		name = "Offline"
+	}
+
+	local := config.TileProvider{
+		Name:        name,
+		URL:         fmt.Sprintf("%s/{z}/{x}/{y}.%s", Directory, manifest.Format),
+		Attribution: manifest.Attribution,
+		MaxZoom:     manifest.MaxZoom,
+	}
+
+	return append([]config.TileProvider{local}, providers...)
+}
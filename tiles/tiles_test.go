@@ -0,0 +1,78 @@
+package tiles
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTileXY(t *testing.T) {
+	tests := []struct {
+		lat, lon float64
+		zoom     int
+		wantX    int
+		wantY    int
+	}{
+		{0, 0, 0, 0, 0},
+		{52.52, 13.405, 10, 550, 335},
+		{-33.86, 151.21, 12, 3768, 2457},
+		{90, 180, 2, 3, 0},
+	}
+
+	for _, tt := range tests {
+		x, y := tileXY(tt.lat, tt.lon, tt.zoom)
+		if x != tt.wantX || y != tt.wantY {
+			t.Errorf("tileXY(%v, %v, %d) = %d, %d, want %d, %d", tt.lat, tt.lon, tt.zoom, x, y, tt.wantX, tt.wantY)
+		}
+	}
+}
+
+func TestImport(t *testing.T) {
+	dir := t.TempDir()
+	// Characters with a meaning in URIs must not break opening the file.
+	mbtilesPath := filepath.Join(dir, "tiles #1.mbtiles")
+
+	db, err := sql.Open("sqlite", mbtilesPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	statements := []string{
+		"CREATE TABLE metadata (name TEXT, value TEXT)",
+		"CREATE TABLE tiles (zoom_level INTEGER, tile_column INTEGER, tile_row INTEGER, tile_data BLOB)",
+		"INSERT INTO metadata VALUES ('name', 'Test'), ('format', 'png'), ('minzoom', '0'), ('maxzoom', '1')",
+		"INSERT INTO tiles VALUES (0, 0, 0, x'00'), (1, 1, 1, x'01'), (1, 1, 0, x'02')",
+	}
+
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_ = db.Close()
+
+	buildDirectory := filepath.Join(dir, "build")
+	box := BoundingBox{MinLat: 10, MinLon: 10, MaxLat: 20, MaxLon: 20}
+
+	written, err := Import(buildDirectory, mbtilesPath, ImportOptions{Boxes: []BoundingBox{box}, MaxZoom: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The north-eastern tile of zoom level 1 is the second row from the south.
+	if written != 2 {
+		t.Errorf("wrote %d tiles, want 2", written)
+	}
+
+	tile, err := os.ReadFile(filepath.Join(buildDirectory, Directory, "1", "1", "0.png"))
+	if err != nil || len(tile) != 1 || tile[0] != 1 {
+		t.Errorf("got tile %v (%v), want [1]", tile, err)
+	}
+
+	providers := Providers(buildDirectory)
+	if providers[0].URL != "tiles/{z}/{x}/{y}.png" || providers[0].MaxZoom != 1 {
+		t.Errorf("got provider %+v, want installed tiles first", providers[0])
+	}
+}